@@ -2,18 +2,48 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"slices"
 	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/driftreport/entities"
+	"github.com/driftreport/handlers"
 	"github.com/driftreport/providers"
 	"github.com/driftreport/services"
 	"github.com/driftreport/utils"
 	"github.com/joho/godotenv"
 )
 
+// version is the version of the tool printed in the json output, set at build time with
+// -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
 func main() {
+	policyFile := flag.String("policy", "", "drift policy file assigning severities, overrides DRIFT_POLICY_FILE")
+	failOn := flag.String("fail-on", "", "exit with an error when a drift at or above this severity is found (critical, high, medium, low, info)")
+	sortBy := flag.String("sort", entities.SortByAddress, "sort the reports, supported: address, severity, type, none (printed as they are compared)")
+	groupBy := flag.String("group-by", "", "group the tabular report, supported: severity")
+	output := flag.String("output", entities.OutputText, "output format, supported: text, json (a single JSON document), jsonl (a JSON line per resource), sarif, junit, markdown, html")
+	workers := flag.Int("workers", 0, "number of resources fetched and compared at the same time, overrides DRIFT_WORKERS")
+	batchSize := flag.Int("batch-size", 0, "number of resources of a type fetched by a call, overrides DRIFT_BATCH_SIZE")
+	timeout := flag.Duration("timeout", 0, "time the run is given to check the resources, e.g. 10m, overrides DRIFT_TIMEOUT")
+	strict := flag.Bool("strict", false, "exit with an error when a resource could not be checked, overrides DRIFT_STRICT")
+	listTypes := flag.Bool("list-types", false, "list the supported terraform resource types and exit")
+	flag.Parse()
+
+	if *listTypes {
+		for _, resourceType := range handlers.NewRegistry(handlers.Clients{}).Types() {
+			fmt.Println(resourceType)
+		}
+		return
+	}
+
 	//initialize zap logging
 	logger := utils.InitZapLog()
 	defer logger.Sync() // Flush any buffered log messages
@@ -44,15 +74,146 @@ func main() {
 		return
 	}
 
+	//initialize the clients of the other AWS services, AWS_S3_ENDPOINT points S3 to an S3 compatible service
+	awsConfig, err := providers.NewAWSConfig(appConfig.AWSRegion)
+	if err != nil {
+		utils.Logger.Sugar().Errorf("error loading AWS config: %v", err)
+		return
+	}
+	clients := handlers.Clients{
+		AWSProvider:    awsProvider,
+		S3:             providers.NewS3Client(awsConfig, appConfig.S3Endpoint),
+		IAM:            providers.NewIAMClient(awsConfig),
+		AutoScaling:    providers.NewAutoScalingClient(awsConfig),
+		RDS:            providers.NewRDSClient(awsConfig),
+		ELB:            providers.NewELBClient(awsConfig),
+		Lambda:         providers.NewLambdaClient(awsConfig),
+		Route53:        providers.NewRoute53Client(awsConfig),
+		CloudControl:   providers.NewCloudControlClient(awsConfig),
+		CloudFormation: providers.NewCloudFormationClient(awsConfig),
+
+		CheckASGInstances: appConfig.CheckASGInstances,
+	}
+
+	//load the ignore rules file and merge the lifecycle ignore_changes declared in the terraform code
+	ignoreRules, err := utils.LoadIgnoreRules(appConfig.RulesFile)
+	if err != nil {
+		utils.Logger.Sugar().Errorf("error loading drift rules: %v", err)
+		return
+	}
+	lifecycleRules, err := utils.ParseLifecycleIgnoreChanges(appConfig.TerraformDir)
+	if err != nil {
+		utils.Logger.Sugar().Errorf("error reading lifecycle ignore_changes: %v", err)
+		return
+	}
+	ignoreRules.Rules = append(ignoreRules.Rules, lifecycleRules...)
+	ignoreRules.Compile()
+
+	if *workers > 0 {
+		appConfig.Workers = *workers
+	}
+	if *batchSize > 0 {
+		appConfig.BatchSize = *batchSize
+	}
+	if *timeout > 0 {
+		appConfig.Timeout = *timeout
+	}
+	if *strict {
+		appConfig.Strict = true
+	}
+
+	//load the drift policy assigning a severity to every difference
+	if *policyFile != "" {
+		appConfig.PolicyFile = *policyFile
+	}
+	policy, err := utils.LoadDriftPolicy(appConfig.PolicyFile)
+	if err != nil {
+		utils.Logger.Sugar().Errorf("error loading drift policy: %v", err)
+		return
+	}
+	if !slices.Contains([]string{entities.SortByAddress, entities.SortBySeverity, entities.SortByType, entities.SortByNone}, *sortBy) {
+		utils.Logger.Sugar().Errorf("invalid -sort value %q", *sortBy)
+		return
+	}
+	if *groupBy != "" && *groupBy != entities.GroupBySeverity {
+		utils.Logger.Sugar().Errorf("invalid -group-by value %q", *groupBy)
+		return
+	}
+	if !slices.Contains([]string{entities.OutputText, entities.OutputJSON, entities.OutputJSONL, entities.OutputSARIF, entities.OutputJUnit,
+		entities.OutputMarkdown, entities.OutputHTML}, *output) {
+		utils.Logger.Sugar().Errorf("invalid -output value %q", *output)
+		return
+	}
+	customRules, err := utils.LoadCustomRules(appConfig.CustomRulesFile)
+	if err != nil {
+		utils.Logger.Sugar().Errorf("error loading custom rules: %v", err)
+		return
+	}
+	var failOnSeverity entities.Severity
+	if *failOn != "" {
+		if failOnSeverity, err = entities.ParseSeverity(*failOn); err != nil {
+			utils.Logger.Sugar().Errorf("invalid -fail-on value: %v", err)
+			return
+		}
+	}
+
+	//the json outputs report the account of the run, a missing account does not prevent the report
+	account := ""
+	if *output == entities.OutputJSON || *output == entities.OutputJSONL {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		account, err = providers.AccountID(ctx, awsConfig)
+		cancel()
+		if err != nil {
+			utils.Logger.Sugar().Warnf("error getting the AWS account: %v", err)
+		}
+	}
+
+	//the sarif output points to the resource blocks of the terraform code
+	locations, err := utils.ParseResourceLocations(appConfig.TerraformDir)
+	if err != nil {
+		utils.Logger.Sugar().Errorf("error reading the terraform resource blocks: %v", err)
+		return
+	}
+
 	//initialize drift report service
-	svc := services.NewDriftReportService(awsProvider)
+	svc := services.NewDriftReportService(handlers.NewRegistry(clients), &entities.ReportOptions{
+		IgnoreRules:         ignoreRules,
+		IncludeReservedTags: appConfig.IncludeReservedTags,
+		Workers:             appConfig.Workers,
+		BatchSize:           appConfig.BatchSize,
+		Strict:              appConfig.Strict,
+		Policy:              policy,
+		CustomRules:         customRules,
+		FailOn:              failOnSeverity,
+		SortBy:              *sortBy,
+		GroupBy:             *groupBy,
+		Output:              *output,
+		ToolVersion:         version,
+		Region:              appConfig.AWSRegion,
+		Account:             account,
+		Locations:           locations,
+	})
 
-	//context.WithTimeout() to allow early exit when deadline is exceeded
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	//context.WithTimeout() to allow early exit when deadline is exceeded, the resources left are reported as errors
+	ctx, cancel := context.WithTimeout(context.Background(), appConfig.Timeout)
 	defer cancel()
 	err = svc.PrintDriftReport(ctx)
 	if err != nil {
+		var customErr *entities.CustomError
+		isCustomErr := errors.As(err, &customErr)
+		//a partial report is printed, the resources that could not be checked are logged as a warning
+		if isCustomErr && customErr.StatusCode == http.StatusMultiStatus {
+			utils.Logger.Sugar().Warnf("drift report is partial: %v", customErr.Err)
+			return
+		}
 		utils.Logger.Sugar().Errorf("error printing drift report: %v", err)
+		//exit with a failure when the drift exceeds the -fail-on threshold or a strict run has errors, so that CI
+		//jobs fail
+		if isCustomErr && (customErr.StatusCode == http.StatusConflict || customErr.StatusCode == http.StatusFailedDependency) {
+			cancel()
+			logger.Sync()
+			os.Exit(1)
+		}
 		return
 	}
 }
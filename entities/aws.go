@@ -1,10 +1,63 @@
 package entities
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 type (
 	EC2Instance struct {
+		Address        string            `json:"address,omitempty"`
 		InstanceType   string            `json:"instance_type"`
 		SecurityGroups []string          `json:"security_groups"`
 		Tags           map[string]string `json:"tags"`
+		// TagsAll is only set from terraform state, it is tags merged with the provider default_tags
+		TagsAll map[string]string `json:"tags_all,omitempty"`
+		// Blocks holds the nested blocks in their terraform shape, e.g. blocks["metadata_options"][0]["http_tokens"]
+		Blocks map[string]interface{} `json:"blocks,omitempty"`
+		// ImageID and KeyName are only read from AWS, to check the instances of auto scaling groups against their
+		// launch template
+		ImageID string `json:"image_id,omitempty"`
+		KeyName string `json:"key_name,omitempty"`
+	}
+
+	// SecurityGroup rules are kept as permission keys, see PermissionKey, one per protocol, port range and source
+	SecurityGroup struct {
+		Address     string            `json:"address,omitempty"`
+		GroupID     string            `json:"group_id"`
+		Name        string            `json:"name"`
+		Description string            `json:"description"`
+		VpcID       string            `json:"vpc_id"`
+		Ingress     []string          `json:"ingress"`
+		Egress      []string          `json:"egress"`
+		Tags        map[string]string `json:"tags"`
+		TagsAll     map[string]string `json:"tags_all,omitempty"`
+	}
+
+	EBSVolume struct {
+		Address          string            `json:"address,omitempty"`
+		VolumeID         string            `json:"volume_id"`
+		AvailabilityZone string            `json:"availability_zone"`
+		Size             int32             `json:"size"`
+		Type             string            `json:"type"`
+		Iops             int32             `json:"iops"`
+		Throughput       int32             `json:"throughput"`
+		Encrypted        bool              `json:"encrypted"`
+		KmsKeyID         string            `json:"kms_key_id"`
+		Tags             map[string]string `json:"tags"`
+		TagsAll          map[string]string `json:"tags_all,omitempty"`
+		// Attachments maps the attached instance ids to their device name, only read from AWS
+		Attachments map[string]string `json:"attachments,omitempty"`
+	}
+
+	// VolumeAttachment is the state of aws_volume_attachment
+	VolumeAttachment struct {
+		Address    string `json:"-"`
+		ID         string `json:"id"`
+		DeviceName string `json:"device_name"`
+		InstanceID string `json:"instance_id"`
+		VolumeID   string `json:"volume_id"`
 	}
 
 	TerraformState struct {
@@ -12,23 +65,203 @@ type (
 			Mode      string      `json:"mode"`
 			Type      string      `json:"type"`
 			Name      string      `json:"name"`
+			Module    string      `json:"module,omitempty"`
 			Instances []*Instance `json:"instances"`
 		} `json:"resources"`
 	}
 
 	Instance struct {
-		Attributes struct {
+		IndexKey interface{} `json:"index_key,omitempty"`
+		// RawAttributes keeps the attributes json so that other resource types than aws_instance can be decoded
+		RawAttributes json.RawMessage `json:"-"`
+		Attributes    struct {
 			InstanceID     string            `json:"id"`
 			Type           string            `json:"instance_type"`
 			Tags           map[string]string `json:"tags"`
+			TagsAll        map[string]string `json:"tags_all"`
 			State          string            `json:"instance_state"`
 			SecurityGroups []string          `json:"security_groups"`
+
+			RootBlockDevice     []interface{} `json:"root_block_device"`
+			EBSBlockDevice      []interface{} `json:"ebs_block_device"`
+			MetadataOptions     []interface{} `json:"metadata_options"`
+			CreditSpecification []interface{} `json:"credit_specification"`
+			NetworkInterface    []interface{} `json:"network_interface"`
 		} `json:"attributes"`
 	}
 
+	// TerraformSecurityGroup is the state of aws_security_group, the rules are set inline in ingress and egress
+	TerraformSecurityGroup struct {
+		ID          string                        `json:"id"`
+		Name        string                        `json:"name"`
+		Description string                        `json:"description"`
+		VpcID       string                        `json:"vpc_id"`
+		Ingress     []*TerraformSecurityGroupRule `json:"ingress"`
+		Egress      []*TerraformSecurityGroupRule `json:"egress"`
+		Tags        map[string]string             `json:"tags"`
+		TagsAll     map[string]string             `json:"tags_all"`
+	}
+
+	// TerraformSecurityGroupRule is an inline rule of aws_security_group or the state of aws_security_group_rule,
+	// which also sets Type, SecurityGroupID and SourceSecurityGroupID
+	TerraformSecurityGroupRule struct {
+		Type                  string   `json:"type"`
+		SecurityGroupID       string   `json:"security_group_id"`
+		SourceSecurityGroupID string   `json:"source_security_group_id"`
+		Protocol              string   `json:"protocol"`
+		FromPort              int32    `json:"from_port"`
+		ToPort                int32    `json:"to_port"`
+		CidrBlocks            []string `json:"cidr_blocks"`
+		Ipv6CidrBlocks        []string `json:"ipv6_cidr_blocks"`
+		PrefixListIDs         []string `json:"prefix_list_ids"`
+		SecurityGroups        []string `json:"security_groups"`
+		Self                  bool     `json:"self"`
+	}
+
+	// TerraformEBSVolume is the state of aws_ebs_volume
+	TerraformEBSVolume struct {
+		ID               string            `json:"id"`
+		AvailabilityZone string            `json:"availability_zone"`
+		Size             int32             `json:"size"`
+		Type             string            `json:"type"`
+		Iops             int32             `json:"iops"`
+		Throughput       int32             `json:"throughput"`
+		Encrypted        bool              `json:"encrypted"`
+		KmsKeyID         string            `json:"kms_key_id"`
+		Tags             map[string]string `json:"tags"`
+		TagsAll          map[string]string `json:"tags_all"`
+	}
+
 	DriftReport struct {
-		InstanceID  string            `json:"instance_id"`
-		Drifted     bool              `json:"drifted"`
-		Differences map[string]string `json:"differences"`
+		InstanceID   string `json:"instance_id,omitempty"`
+		ResourceID   string `json:"resource_id"`
+		Address      string `json:"address,omitempty"`
+		ResourceType string `json:"resource_type,omitempty"`
+		Drifted      bool   `json:"drifted"`
+		// Status is ok, drifted or error, Error holds the reason of a resource that could not be checked
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+		// Unmanaged is set on the reports of the resources found in AWS but not in the terraform state
+		Unmanaged bool `json:"unmanaged,omitempty"`
+		// Severity is the highest severity of the differences
+		Severity    Severity      `json:"severity,omitempty"`
+		Differences []*Difference `json:"differences"`
+		// Suppressed holds the differences matched by an ignore rule, they do not count as drift
+		Suppressed []*Difference `json:"suppressed,omitempty"`
+		// Findings are the custom rules the resource does not satisfy, they count as drift
+		Findings []*Finding `json:"findings,omitempty"`
+	}
+
+	Finding struct {
+		RuleID      string   `json:"rule_id"`
+		Description string   `json:"description"`
+		Severity    Severity `json:"severity"`
+	}
+
+	// Difference is a single attribute that differs between AWS and terraform, Expected is the terraform value and
+	// Actual the AWS one
+	Difference struct {
+		Attribute string   `json:"attribute"`
+		Kind      string   `json:"kind"`
+		Expected  string   `json:"expected"`
+		Actual    string   `json:"actual"`
+		Severity  Severity `json:"severity,omitempty"`
+		// Confidence is only set on the differences that may be wrong, see ConfidenceBestEffort
+		Confidence string `json:"confidence,omitempty"`
 	}
 )
+
+// UnmarshalJSON decodes the aws_instance attributes and keeps the raw attributes
+func (i *Instance) UnmarshalJSON(data []byte) error {
+	type instance Instance
+	var raw struct {
+		instance
+		Attributes json.RawMessage `json:"attributes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*i = Instance(raw.instance)
+	i.RawAttributes = raw.Attributes
+	if len(raw.Attributes) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw.Attributes, &i.Attributes)
+}
+
+// PermissionKey formats a single security group permission as protocol:ports:source, e.g. tcp:22-22:0.0.0.0/0.
+// Protocol numbers are turned into names and ports are "all" for the all traffic protocol, so that the terraform and
+// AWS notations give the same key
+func PermissionKey(protocol string, fromPort, toPort int32, source string) string {
+	switch strings.ToLower(protocol) {
+	case "-1", "all":
+		return fmt.Sprintf("all:all:%s", source)
+	case "6":
+		protocol = "tcp"
+	case "17":
+		protocol = "udp"
+	case "1":
+		protocol = "icmp"
+	case "58":
+		protocol = "icmpv6"
+	}
+	return fmt.Sprintf("%s:%d-%d:%s", strings.ToLower(protocol), fromPort, toPort, source)
+}
+
+// Statuses of a drift report
+const (
+	ReportStatusOK      = "ok"
+	ReportStatusDrifted = "drifted"
+	ReportStatusError   = "error"
+)
+
+// Kinds of change of a difference, an attribute is added when it only exists in AWS and removed when it only exists
+// in terraform
+const (
+	ChangeKindChanged = "changed"
+	ChangeKindAdded   = "added"
+	ChangeKindRemoved = "removed"
+
+	// MissingValue is printed for the side of an added or removed attribute
+	MissingValue = "<missing>"
+	// RedactedValue is printed instead of a secret value, e.g. a Lambda environment variable
+	RedactedValue = "<redacted>"
+
+	// ConfidenceBestEffort marks the differences found through a generic mapping of the terraform attributes, e.g. by
+	// the Cloud Control handlers, rather than by a handler written for the resource type
+	ConfidenceBestEffort = "best-effort"
+)
+
+// NewDifference creates a difference with the kind of change derived from the missing side
+func NewDifference(attribute string, expected, actual interface{}) *Difference {
+	difference := &Difference{
+		Attribute: attribute,
+		Kind:      ChangeKindChanged,
+		Expected:  fmt.Sprintf("%v", expected),
+		Actual:    fmt.Sprintf("%v", actual),
+	}
+	switch {
+	case expected == nil:
+		difference.Kind = ChangeKindAdded
+		difference.Expected = MissingValue
+	case actual == nil:
+		difference.Kind = ChangeKindRemoved
+		difference.Actual = MissingValue
+	}
+	return difference
+}
+
+// Redact hides the values of a difference, only the kind of change is kept
+func (d *Difference) Redact() *Difference {
+	if d.Expected != MissingValue {
+		d.Expected = RedactedValue
+	}
+	if d.Actual != MissingValue {
+		d.Actual = RedactedValue
+	}
+	return d
+}
+
+func (d *Difference) String() string {
+	return fmt.Sprintf("AWS: %s, Terraform: %s", d.Actual, d.Expected)
+}
@@ -1,6 +1,75 @@
 package entities
 
+import "time"
+
 type AppConfig struct {
 	Environment string `env:"ENVIRONMENT"`
 	AWSRegion   string `env:"AWS_REGION"`
+	RulesFile   string `env:"DRIFT_RULES_FILE"`
+	// TerraformDir is scanned for lifecycle ignore_changes declarations in the .tf files
+	TerraformDir string `env:"TERRAFORM_DIR"`
+	// IncludeReservedTags compares the aws:* tags managed by AWS, which terraform cannot set
+	IncludeReservedTags bool   `env:"INCLUDE_RESERVED_TAGS"`
+	PolicyFile          string `env:"DRIFT_POLICY_FILE"`
+	CustomRulesFile     string `env:"CUSTOM_RULES_FILE"`
+	// S3Endpoint points the S3 client to an S3 compatible service instead of AWS
+	S3Endpoint string `env:"AWS_S3_ENDPOINT"`
+	// CheckASGInstances checks the instances of the auto scaling groups against their launch template
+	CheckASGInstances bool `env:"CHECK_ASG_INSTANCES"`
+	// Workers is the number of resources fetched and compared at the same time
+	Workers int `env:"DRIFT_WORKERS" envDefault:"8"`
+	// Strict fails the run when a resource could not be checked
+	Strict bool `env:"DRIFT_STRICT"`
+	// BatchSize is the number of resources of a type fetched by a call
+	BatchSize int `env:"DRIFT_BATCH_SIZE" envDefault:"100"`
+	// Timeout is the time the whole run is given to check the resources
+	Timeout time.Duration `env:"DRIFT_TIMEOUT" envDefault:"5m"`
+}
+
+// ReportOptions holds the settings a drift report run is configured with
+type ReportOptions struct {
+	IgnoreRules         *IgnoreRules
+	IncludeReservedTags bool
+	Policy              *DriftPolicy
+	CustomRules         *CustomRules
+	// FailOn makes the report fail when a drift at or above this severity is found
+	FailOn  Severity
+	SortBy  string
+	GroupBy string
+	// Workers is the size of the worker pools of the report pipeline
+	Workers int
+	// BatchSize is the number of resources fetched by a call of a handler
+	BatchSize int
+	// Strict fails the run when a resource could not be checked, instead of returning a partial report
+	Strict bool
+	// Output is one of the Output values, the metadata of the json outputs are the tool version, region and account
+	Output      string
+	ToolVersion string
+	Region      string
+	Account     string
+	// Locations are the .tf file and line of the resource blocks by address, for the outputs pointing to the source
+	Locations map[string]*SourceLocation
 }
+
+// Values of the sort and group-by options, the reports are sorted by address by default and printed as they are
+// compared with SortByNone
+const (
+	SortByAddress   = "address"
+	SortBySeverity  = "severity"
+	SortByType      = "type"
+	SortByNone      = "none"
+	GroupBySeverity = "severity"
+)
+
+// Values of the output option, text prints the JSON reports followed by their table, json a single document,
+// jsonl a JSON line per report followed by a summary line, sarif a SARIF 2.1.0 log, junit a JUnit XML report and
+// markdown and html a report for people
+const (
+	OutputText     = "text"
+	OutputJSON     = "json"
+	OutputJSONL    = "jsonl"
+	OutputSARIF    = "sarif"
+	OutputJUnit    = "junit"
+	OutputMarkdown = "markdown"
+	OutputHTML     = "html"
+)
@@ -0,0 +1,42 @@
+package entities
+
+import "time"
+
+type (
+	// DriftDocument is the single JSON document printed with the json output, see schema/drift-report.schema.json
+	DriftDocument struct {
+		Metadata *ReportMetadata `json:"metadata"`
+		Reports  []*DriftReport  `json:"reports"`
+	}
+
+	// ReportMetadata describes the run a document was produced by
+	ReportMetadata struct {
+		StartTime    time.Time `json:"start_time"`
+		EndTime      time.Time `json:"end_time"`
+		ToolVersion  string    `json:"tool_version"`
+		StateSources []string  `json:"state_sources"`
+		Region       string    `json:"region,omitempty"`
+		Account      string    `json:"account,omitempty"`
+		// Counts are the number of reports by status, and their total
+		Counts map[string]int `json:"counts"`
+	}
+
+	// SourceLocation is the place of a resource block in the terraform code
+	SourceLocation struct {
+		File string
+		Line int
+	}
+
+	// DriftRecord is a line of the jsonl output, a report or the summary of the run printed last
+	DriftRecord struct {
+		Type string `json:"type"`
+		*DriftReport
+		*ReportMetadata
+	}
+)
+
+// Types of the records of the jsonl output
+const (
+	RecordTypeReport  = "report"
+	RecordTypeSummary = "summary"
+)
@@ -10,3 +10,7 @@ type CustomError struct {
 func (c *CustomError) Error() string {
 	return fmt.Sprintf("failed with code %d: %s", c.StatusCode, c.Err)
 }
+
+func (c *CustomError) Unwrap() error {
+	return c.Err
+}
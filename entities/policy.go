@@ -0,0 +1,120 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+	SeverityInfo     Severity = "info"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityLow:      2,
+	SeverityMedium:   3,
+	SeverityHigh:     4,
+	SeverityCritical: 5,
+}
+
+// Rank orders the severities from info (1) to critical (5), an unknown severity ranks 0
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+// ParseSeverity validates a severity name given in a policy file or on the command line
+func ParseSeverity(value string) (Severity, error) {
+	severity := Severity(strings.ToLower(value))
+	if severity.Rank() == 0 {
+		return "", fmt.Errorf("unknown severity %q, expected one of critical, high, medium, low, info", value)
+	}
+	return severity, nil
+}
+
+type (
+	// DriftPolicy is the content of the drift policy file, the first matching rule gives the severity of a
+	// difference and DefaultSeverity is used when no rule matches
+	DriftPolicy struct {
+		DefaultSeverity Severity      `json:"default_severity"`
+		Rules           []*PolicyRule `json:"rules"`
+	}
+
+	// PolicyRule matches differences by resource type, attribute path, change kind and AWS value. Empty fields
+	// match anything, ResourceType, Attribute and Actual accept * and ? wildcards
+	PolicyRule struct {
+		ResourceType string   `json:"resource_type,omitempty"`
+		Attribute    string   `json:"attribute,omitempty"`
+		Kind         string   `json:"kind,omitempty"`
+		Actual       string   `json:"actual,omitempty"`
+		Severity     Severity `json:"severity"`
+		// globs are the compiled ResourceType, Attribute and Actual, see DriftPolicy.Compile
+		globs *policyGlobs
+	}
+
+	policyGlobs struct {
+		resourceType, attribute, actual glob
+	}
+)
+
+// DefaultDriftPolicy is used when no policy file is configured
+func DefaultDriftPolicy() *DriftPolicy {
+	policy := &DriftPolicy{
+		DefaultSeverity: SeverityMedium,
+		Rules: []*PolicyRule{
+			{Attribute: "resource", Kind: ChangeKindRemoved, Severity: SeverityCritical},
+			{Attribute: "metadata_options[*].http_tokens", Actual: "optional", Severity: SeverityCritical},
+			{ResourceType: "aws_security_group", Attribute: "ingress.*:0.0.0.0/0", Kind: ChangeKindAdded, Severity: SeverityCritical},
+			{ResourceType: "aws_security_group", Attribute: "ingress.*:::/0", Kind: ChangeKindAdded, Severity: SeverityCritical},
+			{ResourceType: "aws_security_group", Attribute: "ingress.*", Kind: ChangeKindAdded, Severity: SeverityHigh},
+			{Attribute: "security_groups.*", Kind: ChangeKindAdded, Severity: SeverityHigh},
+			{Attribute: "security_groups.*", Severity: SeverityMedium},
+			{Attribute: "metadata_options[*].*", Severity: SeverityHigh},
+			{Attribute: "tags.*", Severity: SeverityLow},
+			{Attribute: "default_tags.*", Severity: SeverityLow},
+		},
+	}
+	policy.Compile()
+	return policy
+}
+
+// Compile compiles the wildcards of the rules once, instead of for every difference they classify. A rule that is
+// not compiled is compiled on every match
+func (p *DriftPolicy) Compile() {
+	if p == nil {
+		return
+	}
+	for _, rule := range p.Rules {
+		rule.globs = rule.compile()
+	}
+}
+
+// Classify returns the severity of a difference on a resource of the given type
+func (p *DriftPolicy) Classify(resourceType string, difference *Difference) Severity {
+	if p == nil {
+		return SeverityMedium
+	}
+	for _, rule := range p.Rules {
+		globs := rule.globs
+		if globs == nil {
+			globs = rule.compile()
+		}
+		if globs.resourceType.match(resourceType) && globs.attribute.match(difference.Attribute) &&
+			(rule.Kind == "" || rule.Kind == difference.Kind) && globs.actual.match(difference.Actual) {
+			return rule.Severity
+		}
+	}
+	if p.DefaultSeverity == "" {
+		return SeverityMedium
+	}
+	return p.DefaultSeverity
+}
+
+func (r *PolicyRule) compile() *policyGlobs {
+	return &policyGlobs{resourceType: compileGlob(r.ResourceType), attribute: compileGlob(r.Attribute), actual: compileGlob(r.Actual)}
+}
@@ -0,0 +1,34 @@
+package entities
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDriftPolicy(t *testing.T) {
+	policy := &DriftPolicy{
+		DefaultSeverity: SeverityInfo,
+		Rules: []*PolicyRule{
+			{ResourceType: "aws_instance", Attribute: "instance_type", Severity: SeverityHigh},
+			{Attribute: "tags.Owner", Kind: ChangeKindRemoved, Severity: SeverityCritical},
+		},
+	}
+
+	Convey("first matching rule gives the severity", t, func() {
+		So(policy.Classify("aws_instance", NewDifference("instance_type", "t2.micro", "t2.large")), ShouldEqual, SeverityHigh)
+		So(policy.Classify("aws_launch_template", NewDifference("instance_type", "t2.micro", "t2.large")), ShouldEqual, SeverityInfo)
+		So(policy.Classify("aws_instance", NewDifference("tags.Owner", "ops", nil)), ShouldEqual, SeverityCritical)
+		So(policy.Classify("aws_instance", NewDifference("tags.Owner", "ops", "dev")), ShouldEqual, SeverityInfo)
+	})
+
+	Convey("parse and rank severities", t, func() {
+		severity, err := ParseSeverity("HIGH")
+		So(err, ShouldBeNil)
+		So(severity, ShouldEqual, SeverityHigh)
+		So(SeverityCritical.Rank(), ShouldBeGreaterThan, severity.Rank())
+
+		_, err = ParseSeverity("urgent")
+		So(err, ShouldNotBeNil)
+	})
+}
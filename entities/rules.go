@@ -0,0 +1,138 @@
+package entities
+
+import (
+	"regexp"
+	"strings"
+)
+
+type (
+	// IgnoreRules is the content of the drift rules file
+	IgnoreRules struct {
+		Rules []*IgnoreRule `json:"rules"`
+	}
+
+	// IgnoreRule suppresses differences on the attributes matched by Attributes for the resources matched by Address.
+	// Both fields accept * and ? wildcards, e.g. {"address": "aws_instance.*", "attributes": ["tags.aws:*"]}
+	IgnoreRule struct {
+		Address    string   `json:"address"`
+		Attributes []string `json:"attributes"`
+		Reason     string   `json:"reason,omitempty"`
+		// globs are the compiled Address and Attributes, see IgnoreRules.Compile
+		globs *ignoreGlobs
+	}
+
+	ignoreGlobs struct {
+		address    glob
+		attributes []glob
+	}
+
+	// glob is a compiled * and ? wildcard pattern, the empty pattern matches anything
+	glob struct {
+		re *regexp.Regexp
+	}
+)
+
+// Compile compiles the wildcards of the rules once, instead of for every difference they are matched with. Rules
+// added afterwards must be compiled again, a rule that is not compiled is compiled on every match
+func (r *IgnoreRules) Compile() {
+	if r == nil {
+		return
+	}
+	for _, rule := range r.Rules {
+		rule.globs = rule.compile()
+	}
+}
+
+// Match returns the first rule ignoring the attribute path of the resource address, or nil when none applies.
+// A rule on a parent attribute also ignores its children, so "tags" covers "tags.Name", and a rule on
+// aws_instance.web also covers the count/for_each instances aws_instance.web[0], also within module instances
+func (r *IgnoreRules) Match(address, attribute string) *IgnoreRule {
+	if r == nil {
+		return nil
+	}
+	configAddress := ConfigAddress(address)
+	for _, rule := range r.Rules {
+		globs := rule.globs
+		if globs == nil {
+			globs = rule.compile()
+		}
+		if !globs.address.match(address) && !globs.address.match(configAddress) {
+			continue
+		}
+		for i, pattern := range rule.Attributes {
+			if pattern == "*" || globs.attributes[i].match(attribute) || strings.HasPrefix(attribute, pattern+".") {
+				return rule
+			}
+		}
+	}
+	return nil
+}
+
+func (r *IgnoreRule) compile() *ignoreGlobs {
+	globs := &ignoreGlobs{address: compileGlob(r.Address), attributes: make([]glob, 0, len(r.Attributes))}
+	for _, pattern := range r.Attributes {
+		globs.attributes = append(globs.attributes, compileGlob(pattern))
+	}
+	return globs
+}
+
+// ConfigAddress drops the instance keys of a resource address, so that module.a[0].aws_x.y["k"] is the address of
+// the module.a.aws_x.y block in the terraform code
+func ConfigAddress(address string) string {
+	var builder strings.Builder
+	depth, inString := 0, false
+	for i := 0; i < len(address); i++ {
+		switch c := address[i]; {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case depth > 0 && c == '"':
+			inString = true
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// compileGlob compiles a pattern where * matches any run of characters and ? a single one. Unlike path.Match, *
+// also matches "/" so tag keys such as kubernetes.io/cluster/* can be globbed
+func compileGlob(pattern string) glob {
+	if pattern == "" {
+		return glob{}
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return glob{re: regexp.MustCompile("^" + expr + "$")}
+}
+
+func (g glob) match(value string) bool {
+	return g.re == nil || g.re.MatchString(value)
+}
+
+type (
+	// CustomRules is the content of the custom drift rules file
+	CustomRules struct {
+		Rules []*CustomRule `json:"rules"`
+	}
+
+	// CustomRule is an expression evaluated against the terraform and aws values of a resource, a resource that
+	// does not satisfy the condition gets a finding. When Attribute is set, differences on that attribute are
+	// allowed as long as the condition holds, e.g. an instance_type change within the same family
+	CustomRule struct {
+		ID           string   `json:"id"`
+		Description  string   `json:"description"`
+		ResourceType string   `json:"resource_type,omitempty"`
+		Attribute    string   `json:"attribute,omitempty"`
+		Condition    string   `json:"condition"`
+		Severity     Severity `json:"severity"`
+	}
+)
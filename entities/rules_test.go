@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules := &IgnoreRules{
+		Rules: []*IgnoreRule{
+			{Address: "aws_instance.*", Attributes: []string{"tags.aws:*", "tags.kubernetes.io/*"}},
+			{Address: "aws_instance.web", Attributes: []string{"tags"}},
+		},
+	}
+
+	Convey("glob on tag keys", t, func() {
+		So(rules.Match("aws_instance.example", "tags.aws:backup:source-resource"), ShouldNotBeNil)
+		So(rules.Match("aws_instance.example", "tags.kubernetes.io/cluster/main"), ShouldNotBeNil)
+		So(rules.Match("aws_instance.example", "tags.Name"), ShouldBeNil)
+		So(rules.Match("aws_instance.example", "instance_type"), ShouldBeNil)
+	})
+
+	Convey("parent attribute covers its children and indexed addresses", t, func() {
+		So(rules.Match("aws_instance.web", "tags.Name"), ShouldNotBeNil)
+		So(rules.Match("aws_instance.web[0]", "tags.Name"), ShouldNotBeNil)
+		So(rules.Match("aws_instance.webserver", "tags.Name"), ShouldBeNil)
+	})
+
+	Convey("nil rules ignore nothing", t, func() {
+		var empty *IgnoreRules
+		So(empty.Match("aws_instance.web", "tags"), ShouldBeNil)
+	})
+
+	Convey("compiled rules match like the rules they are compiled from, within module instances too", t, func() {
+		compiled := &IgnoreRules{Rules: []*IgnoreRule{
+			{Address: "module.network.aws_subnet.*", Attributes: []string{"tags.aws:*", "map_public_ip_on_launch"}},
+		}}
+		compiled.Compile()
+		So(compiled.Match(`module.network["eu-west-1"].aws_subnet.private[0]`, "tags.aws:cloudformation:stack-name"), ShouldNotBeNil)
+		So(compiled.Match("module.network.aws_subnet.public", "map_public_ip_on_launch"), ShouldNotBeNil)
+		So(compiled.Match("module.network.aws_subnet.public", "tags.Name"), ShouldBeNil)
+		So(compiled.Match("module.edge.aws_subnet.public", "map_public_ip_on_launch"), ShouldBeNil)
+	})
+}
+
+func TestConfigAddress(t *testing.T) {
+	Convey("the instance keys of resources and modules are dropped", t, func() {
+		So(ConfigAddress("aws_instance.web"), ShouldEqual, "aws_instance.web")
+		So(ConfigAddress("aws_instance.web[0]"), ShouldEqual, "aws_instance.web")
+		So(ConfigAddress(`module.a[0].module.b["x"].aws_s3_bucket.logs["a[1].b"]`), ShouldEqual, "module.a.module.b.aws_s3_bucket.logs")
+		So(ConfigAddress(`aws_route53_record.unmanaged["Z1_\"quoted\"]_A"]`), ShouldEqual, "aws_route53_record.unmanaged")
+	})
+}
@@ -0,0 +1,360 @@
+package autoscaling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsautoscaling "github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const (
+	ResourceTypeGroup          = "aws_autoscaling_group"
+	ResourceTypeLaunchTemplate = "aws_launch_template"
+)
+
+// setAttributes are the unordered lists of the resources, they are compared value by value
+var setAttributes = []string{"target_group_arns", "vpc_security_group_ids", "vpc_zone_identifier"}
+
+type (
+	// API is the part of the Auto Scaling client read by the handlers
+	API interface {
+		DescribeAutoScalingGroups(ctx context.Context, params *awsautoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*awsautoscaling.Options)) (*awsautoscaling.DescribeAutoScalingGroupsOutput, error)
+	}
+
+	// GroupHandler checks the drift of aws_autoscaling_group resources, and optionally of the instances of the groups
+	// against the launch template version the group launches and the version they were launched from
+	GroupHandler struct {
+		client      API
+		awsProvider providers.AWSProvider
+		// checkInstances reads the instances of the groups and checks them against their launch template
+		checkInstances bool
+	}
+
+	// LaunchTemplateHandler checks the drift of aws_launch_template resources against their latest version
+	LaunchTemplateHandler struct {
+		awsProvider providers.AWSProvider
+	}
+
+	// group is an auto scaling group from AWS with its instances and the launch template version it launches
+	group struct {
+		values    map[string]interface{}
+		instances []member
+		// templateID and template are the launch template version of the group, template is nil when the group has no
+		// launch template or no instances
+		templateID string
+		template   map[string]interface{}
+	}
+
+	// member is an instance of an auto scaling group with the launch template version it was launched from. template
+	// is the contents of that version and instance the instance read from EC2, either is nil when it was not found
+	member struct {
+		id           string
+		instanceType string
+		templateID   string
+		version      string
+		template     map[string]interface{}
+		instance     *entities.EC2Instance
+	}
+)
+
+// NewHandlers creates the handlers of the auto scaling resource types, checkInstances checks the instances of the
+// groups as well
+func NewHandlers(client API, awsProvider providers.AWSProvider, checkInstances bool) []registry.Handler {
+	return []registry.Handler{
+		&GroupHandler{client: client, awsProvider: awsProvider, checkInstances: checkInstances},
+		&LaunchTemplateHandler{awsProvider: awsProvider},
+	}
+}
+
+func (h *GroupHandler) Types() []string {
+	return []string{ResourceTypeGroup}
+}
+
+// Normalize decodes the auto scaling groups, their tag blocks are turned into the tags of the group
+func (h *GroupHandler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources, err := normalize(state, ResourceTypeGroup)
+	if err != nil {
+		return nil, err
+	}
+	for _, resource := range resources {
+		tags := make(map[string]string)
+		tagBlocks, _ := resource.Object.(map[string]interface{})["tag"].([]interface{})
+		for _, tag := range tagBlocks {
+			if tag, ok := tag.(map[string]interface{}); ok {
+				tags[utils.StringValue(tag["key"])] = utils.StringValue(tag["value"])
+			}
+		}
+		resource.Values["tags"] = tags
+	}
+	return resources, nil
+}
+
+// Fetch gets the auto scaling groups from AWS in their terraform shape. When the instances are checked, it gets the
+// instances of the groups as well, with the launch template version every instance was launched from and the instances
+// from EC2. The groups that do not exist any more are left out, as are the versions and instances of a group deleted
+// in the meantime
+func (h *GroupHandler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	awsResources := make(map[string]*registry.Resource, len(resources))
+	if len(resources) == 0 {
+		return awsResources, nil
+	}
+
+	// the launch template versions are shared by the instances of the groups, they are read once per version
+	templates := make(map[string]map[string]interface{})
+	templateVersion := func(templateID, version string) (map[string]interface{}, error) {
+		key := templateID + ":" + version
+		if template, ok := templates[key]; ok {
+			return template, nil
+		}
+		template, err := h.awsProvider.GetLaunchTemplateVersion(ctx, templateID, version)
+		if err != nil {
+			return nil, err
+		}
+		templates[key] = template
+		return template, nil
+	}
+	awsGroups := make([]*group, 0, len(resources))
+	instanceIDs := make([]string, 0)
+
+	paginator := awsautoscaling.NewDescribeAutoScalingGroupsPaginator(h.client, &awsautoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: registry.IDs(resources),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, describeError("auto scaling groups", err)
+		}
+		for _, autoScalingGroup := range page.AutoScalingGroups {
+			name := aws.ToString(autoScalingGroup.AutoScalingGroupName)
+			tags := make(map[string]string, len(autoScalingGroup.Tags))
+			for _, tag := range autoScalingGroup.Tags {
+				tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+			subnets := make([]string, 0)
+			if zones := aws.ToString(autoScalingGroup.VPCZoneIdentifier); zones != "" {
+				subnets = strings.Split(zones, ",")
+			}
+			awsGroup := &group{values: map[string]interface{}{
+				"min_size":                  aws.ToInt32(autoScalingGroup.MinSize),
+				"max_size":                  aws.ToInt32(autoScalingGroup.MaxSize),
+				"desired_capacity":          aws.ToInt32(autoScalingGroup.DesiredCapacity),
+				"health_check_type":         aws.ToString(autoScalingGroup.HealthCheckType),
+				"health_check_grace_period": aws.ToInt32(autoScalingGroup.HealthCheckGracePeriod),
+				"vpc_zone_identifier":       subnets,
+				"target_group_arns":         append([]string{}, autoScalingGroup.TargetGroupARNs...),
+				"tags":                      tags,
+			}}
+
+			// the instances and the launch template versions are only read when they are checked
+			instances := autoScalingGroup.Instances
+			if !h.checkInstances {
+				instances = nil
+			}
+
+			// a group launching from a mixed instances policy has no launch template of its own
+			if template := autoScalingGroup.LaunchTemplate; template != nil {
+				awsGroup.values["launch_template"] = []interface{}{map[string]interface{}{
+					"id":      aws.ToString(template.LaunchTemplateId),
+					"name":    aws.ToString(template.LaunchTemplateName),
+					"version": aws.ToString(template.Version),
+				}}
+				awsGroup.templateID = aws.ToString(template.LaunchTemplateId)
+				if len(instances) > 0 {
+					version := aws.ToString(template.Version)
+					if version == "" {
+						version = "$Default"
+					}
+					awsGroup.template, err = templateVersion(awsGroup.templateID, version)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+			for _, instance := range instances {
+				instanceMember := member{id: aws.ToString(instance.InstanceId), instanceType: aws.ToString(instance.InstanceType)}
+				if instance.LaunchTemplate != nil {
+					instanceMember.templateID = aws.ToString(instance.LaunchTemplate.LaunchTemplateId)
+					instanceMember.version = aws.ToString(instance.LaunchTemplate.Version)
+					instanceMember.template, err = templateVersion(instanceMember.templateID, instanceMember.version)
+					if err != nil {
+						return nil, err
+					}
+				}
+				awsGroup.instances = append(awsGroup.instances, instanceMember)
+				instanceIDs = append(instanceIDs, instanceMember.id)
+			}
+			awsGroups = append(awsGroups, awsGroup)
+			awsResources[name] = &registry.Resource{Type: ResourceTypeGroup, ID: name, Object: awsGroup, Values: awsGroup.values}
+		}
+	}
+
+	if len(instanceIDs) == 0 {
+		return awsResources, nil
+	}
+	ec2Instances, err := h.awsProvider.GetEC2Instances(ctx, instanceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, awsGroup := range awsGroups {
+		for i := range awsGroup.instances {
+			awsGroup.instances[i].instance = ec2Instances[awsGroup.instances[i].id]
+		}
+	}
+	return awsResources, nil
+}
+
+// Compare compares an auto scaling group from AWS with the terraform state. When the instances are checked, every
+// instance of the group is checked against the launch template version the group launches, e.g.
+// instances.i-0abc.launch_template.version, and against the version it was launched from, e.g.
+// instances.i-0abc.instance_type
+func (h *GroupHandler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	differences := compareValues(tfResource, awsResource.Values, options)
+	if !h.checkInstances {
+		return differences, nil
+	}
+
+	awsGroup := awsResource.Object.(*group)
+	if awsGroup.template == nil {
+		return differences, nil
+	}
+	version := fmt.Sprintf("%v", awsGroup.template["version_number"])
+	for _, instance := range awsGroup.instances {
+		path := "instances." + instance.id
+		if instance.templateID != awsGroup.templateID {
+			differences = append(differences, entities.NewDifference(path+".launch_template.id", awsGroup.templateID, instance.templateID))
+		} else if instance.version != version {
+			differences = append(differences, entities.NewDifference(path+".launch_template.version", version, instance.version))
+		}
+		differences = append(differences, compareInstance(path, instance)...)
+	}
+	return differences, nil
+}
+
+// compareInstance compares an instance of a group with the launch template version it was launched from, the
+// attributes the version does not set are not compared
+func compareInstance(path string, instance member) []*entities.Difference {
+	differences := make([]*entities.Difference, 0)
+	if instance.template == nil {
+		return differences
+	}
+	// the instance type of a launch template is overridden by the instance requirements of a group
+	if instanceType := utils.StringValue(instance.template["instance_type"]); instanceType != "" && instance.instanceType != instanceType {
+		differences = append(differences, entities.NewDifference(path+".instance_type", instanceType, instance.instanceType))
+	}
+	if instance.instance == nil {
+		return differences
+	}
+	for _, attribute := range []struct{ key, value string }{
+		{"image_id", instance.instance.ImageID},
+		{"key_name", instance.instance.KeyName},
+	} {
+		if expected := utils.StringValue(instance.template[attribute.key]); expected != "" && attribute.value != expected {
+			differences = append(differences, entities.NewDifference(path+"."+attribute.key, expected, attribute.value))
+		}
+	}
+	if securityGroups, _ := instance.template["vpc_security_group_ids"].([]string); len(securityGroups) > 0 {
+		differences = append(differences, utils.SetDifferences(path+".vpc_security_group_ids", instance.instance.SecurityGroups, securityGroups)...)
+	}
+	return differences
+}
+
+func (h *LaunchTemplateHandler) Types() []string {
+	return []string{ResourceTypeLaunchTemplate}
+}
+
+// Normalize decodes the launch templates of the state
+func (h *LaunchTemplateHandler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	return normalize(state, ResourceTypeLaunchTemplate)
+}
+
+// Fetch gets the launch templates from AWS with the contents of their latest version
+func (h *LaunchTemplateHandler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	valuesMap, err := h.awsProvider.GetLaunchTemplates(ctx, registry.IDs(resources))
+	if err != nil {
+		return nil, err
+	}
+	awsResources := make(map[string]*registry.Resource, len(valuesMap))
+	for id, values := range valuesMap {
+		awsResources[id] = &registry.Resource{Type: ResourceTypeLaunchTemplate, ID: id, Object: values, Values: values}
+	}
+	return awsResources, nil
+}
+
+// Compare compares a launch template from AWS with the terraform state, a version created outside terraform is
+// reported on latest_version and on the contents it changed
+func (h *LaunchTemplateHandler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	return compareValues(tfResource, awsResource.Values, options), nil
+}
+
+// normalize decodes the resources of a type from the state, the values get the tags merged with tags_all
+func normalize(state registry.State, resourceType string) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[resourceType]))
+	for _, stateResource := range state[resourceType] {
+		attributes := make(map[string]interface{})
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &attributes); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+		values := make(map[string]interface{}, len(attributes))
+		for key, value := range attributes {
+			values[key] = value
+		}
+		values["tags"] = map[string]string{}
+		if tags := utils.MergedTags(utils.StringMap(attributes["tags"]), utils.StringMap(attributes["tags_all"])); tags != nil {
+			values["tags"] = tags
+		}
+		resources = append(resources, &registry.Resource{
+			Type:    resourceType,
+			Address: stateResource.Address,
+			ID:      utils.StringValue(attributes["id"]),
+			Object:  attributes,
+			Values:  values,
+		})
+	}
+	return resources, nil
+}
+
+// compareValues compares the attributes read from AWS with the state, tags by key and sets value by value
+func compareValues(tfResource *registry.Resource, awsValues map[string]interface{}, options *entities.ReportOptions) []*entities.Difference {
+	tfValues := tfResource.Object.(map[string]interface{})
+	keys := utils.SortedKeys(awsValues)
+
+	differences := make([]*entities.Difference, 0)
+	for _, key := range keys {
+		switch {
+		case key == "tags":
+			// the tags of a group are tag blocks, without provider default_tags
+			awsTags, _ := awsValues[key].(map[string]string)
+			tags, tagsAll := utils.StringMap(tfValues["tags"]), utils.StringMap(tfValues["tags_all"])
+			if tfResource.Type == ResourceTypeGroup {
+				tags, _ = tfResource.Values["tags"].(map[string]string)
+			}
+			differences = append(differences, utils.TagDifferences(awsTags, tags, tagsAll, options.IncludeReservedTags)...)
+		case slices.Contains(setAttributes, key):
+			awsSet, _ := awsValues[key].([]string)
+			differences = append(differences, utils.SetDifferences(key, awsSet, utils.StringList(tfValues[key]))...)
+		default:
+			differences = append(differences, utils.CompareNested(key, awsValues[key], tfValues[key])...)
+		}
+	}
+	return differences
+}
+
+func describeError(resource string, err error) error {
+	utils.Logger.Sugar().Errorf("failed to describe %s: %v", resource, err)
+	return &entities.CustomError{
+		StatusCode: http.StatusBadRequest,
+		Err:        err,
+	}
+}
@@ -0,0 +1,185 @@
+package autoscaling
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsautoscaling "github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/registry/registrytest"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// autoScalingClient returns the same groups for every call
+type autoScalingClient struct {
+	groups []types.AutoScalingGroup
+}
+
+func (c *autoScalingClient) DescribeAutoScalingGroups(ctx context.Context, params *awsautoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*awsautoscaling.Options)) (*awsautoscaling.DescribeAutoScalingGroupsOutput, error) {
+	return &awsautoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: c.groups}, nil
+}
+
+// launchTemplateProvider returns a launch template whose latest version 3 was created outside terraform, the
+// instances i-0aaa launched from version 3 and i-0bbb launched from version 2
+type launchTemplateProvider struct {
+	providers.AWSProvider
+}
+
+func (p *launchTemplateProvider) GetEC2Instances(ctx context.Context, instanceIDs []string) (map[string]*entities.EC2Instance, error) {
+	return map[string]*entities.EC2Instance{
+		"i-0aaa": {InstanceType: "t3.large", ImageID: "ami-0123456789abcdef0", SecurityGroups: []string{"sg-0a1b2c3d", "sg-0e0e0e0e"}},
+		"i-0bbb": {InstanceType: "t3.small", ImageID: "ami-0fedcba9876543210", SecurityGroups: []string{"sg-0a1b2c3d"}},
+	}, nil
+}
+
+func (p *launchTemplateProvider) GetLaunchTemplates(ctx context.Context, templateIDs []string) (map[string]map[string]interface{}, error) {
+	values, _ := p.GetLaunchTemplateVersion(ctx, templateIDs[0], "3")
+	delete(values, "version_number")
+	values["name"] = "web"
+	values["default_version"] = int64(2)
+	values["latest_version"] = int64(3)
+	values["tags"] = map[string]string{"Name": "web"}
+	return map[string]map[string]interface{}{templateIDs[0]: values}, nil
+}
+
+func (p *launchTemplateProvider) GetLaunchTemplateVersion(ctx context.Context, templateID, version string) (map[string]interface{}, error) {
+	if version == "2" {
+		return map[string]interface{}{
+			"version_number":         int64(2),
+			"description":            "",
+			"image_id":               "ami-0fedcba9876543210",
+			"instance_type":          "t3.medium",
+			"key_name":               "",
+			"user_data":              "",
+			"vpc_security_group_ids": []string{"sg-0a1b2c3d"},
+		}, nil
+	}
+	return map[string]interface{}{
+		"version_number":         int64(3),
+		"description":            "",
+		"image_id":               "ami-0123456789abcdef0",
+		"instance_type":          "t3.large",
+		"key_name":               "",
+		"user_data":              "",
+		"vpc_security_group_ids": []string{"sg-0a1b2c3d"},
+	}, nil
+}
+
+// goneProvider is a launchTemplateProvider whose version 2 was deleted and whose instance i-0bbb was terminated, the
+// provider leaves both out
+type goneProvider struct {
+	launchTemplateProvider
+}
+
+func (p *goneProvider) GetEC2Instances(ctx context.Context, instanceIDs []string) (map[string]*entities.EC2Instance, error) {
+	instances, _ := p.launchTemplateProvider.GetEC2Instances(ctx, instanceIDs)
+	delete(instances, "i-0bbb")
+	return instances, nil
+}
+
+func (p *goneProvider) GetLaunchTemplateVersion(ctx context.Context, templateID, version string) (map[string]interface{}, error) {
+	if version == "2" {
+		return nil, nil
+	}
+	return p.launchTemplateProvider.GetLaunchTemplateVersion(ctx, templateID, version)
+}
+
+func TestAutoScalingHandlers(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	state, err := registry.LoadState("../../testdata/autoscaling.tfstate.json")
+	client := &autoScalingClient{groups: []types.AutoScalingGroup{{
+		AutoScalingGroupName:   aws.String("web"),
+		MinSize:                aws.Int32(2),
+		MaxSize:                aws.Int32(4),
+		DesiredCapacity:        aws.Int32(3),
+		HealthCheckType:        aws.String("ELB"),
+		HealthCheckGracePeriod: aws.Int32(300),
+		VPCZoneIdentifier:      aws.String("subnet-4e5f6a7b,subnet-0a1b2c3d"),
+		TargetGroupARNs:        []string{"arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/web/0123456789abcdef"},
+		LaunchTemplate: &types.LaunchTemplateSpecification{
+			LaunchTemplateId:   aws.String("lt-0a1b2c3d4e5f60718"),
+			LaunchTemplateName: aws.String("web"),
+			Version:            aws.String("$Latest"),
+		},
+		Tags: []types.TagDescription{
+			{Key: aws.String("Name"), Value: aws.String("web")},
+			{Key: aws.String("Team"), Value: aws.String("platform")},
+		},
+		Instances: []types.Instance{
+			{
+				InstanceId:     aws.String("i-0aaa"),
+				InstanceType:   aws.String("t3.large"),
+				LaunchTemplate: &types.LaunchTemplateSpecification{LaunchTemplateId: aws.String("lt-0a1b2c3d4e5f60718"), Version: aws.String("3")},
+			},
+			{
+				InstanceId:     aws.String("i-0bbb"),
+				InstanceType:   aws.String("t3.small"),
+				LaunchTemplate: &types.LaunchTemplateSpecification{LaunchTemplateId: aws.String("lt-0a1b2c3d4e5f60718"), Version: aws.String("2")},
+			},
+		},
+	}}}
+	handlers := registry.New(NewHandlers(client, &launchTemplateProvider{}, false)...)
+	instanceHandlers := registry.New(NewHandlers(client, &launchTemplateProvider{}, true)...)
+
+	Convey("a scaled group is reported, subnets and tags in another order are not", t, func() {
+		So(err, ShouldBeNil)
+		results, err := registrytest.Check(handlers, ResourceTypeGroup, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_autoscaling_group.web"].Differences
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "desired_capacity")
+		So(differences[0].Expected, ShouldEqual, "2")
+		So(differences[0].Actual, ShouldEqual, "3")
+	})
+
+	Convey("without CheckASGInstances the instances and the launch template versions of the groups are not read", t, func() {
+		// the provider is nil, any read of an instance or a version would panic
+		results, err := registrytest.Check(registry.New(NewHandlers(client, nil, false)...), ResourceTypeGroup, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(results["aws_autoscaling_group.web"].Differences, ShouldHaveLength, 1)
+	})
+
+	Convey("instances are checked against the version of the group and the version they were launched from with CheckASGInstances", t, func() {
+		results, err := registrytest.Check(instanceHandlers, ResourceTypeGroup, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_autoscaling_group.web"].Differences
+		So(differences, ShouldHaveLength, 4)
+		So(differences[1].Attribute, ShouldEqual, "instances.i-0aaa.vpc_security_group_ids.sg-0e0e0e0e")
+		So(differences[1].Kind, ShouldEqual, entities.ChangeKindAdded)
+		So(differences[2].Attribute, ShouldEqual, "instances.i-0bbb.launch_template.version")
+		So(differences[2].Expected, ShouldEqual, "3")
+		So(differences[2].Actual, ShouldEqual, "2")
+		// i-0bbb runs the image of version 2, its instance type was changed from the t3.medium of version 2
+		So(differences[3].Attribute, ShouldEqual, "instances.i-0bbb.instance_type")
+		So(differences[3].Expected, ShouldEqual, "t3.medium")
+		So(differences[3].Actual, ShouldEqual, "t3.small")
+	})
+
+	Convey("a deleted launch template version or a terminated instance does not fail the group", t, func() {
+		gone := registry.New(NewHandlers(client, &goneProvider{}, true)...)
+		results, err := registrytest.Check(gone, ResourceTypeGroup, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_autoscaling_group.web"].Differences
+		So(differences, ShouldHaveLength, 3)
+		So(differences[1].Attribute, ShouldEqual, "instances.i-0aaa.vpc_security_group_ids.sg-0e0e0e0e")
+		So(differences[2].Attribute, ShouldEqual, "instances.i-0bbb.launch_template.version")
+	})
+
+	Convey("a launch template version created outside terraform is reported", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypeLaunchTemplate, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_launch_template.web"].Differences
+		So(differences, ShouldHaveLength, 2)
+		So(differences[0].Attribute, ShouldEqual, "instance_type")
+		So(differences[0].Actual, ShouldEqual, "t3.large")
+		So(differences[1].Attribute, ShouldEqual, "latest_version")
+		So(differences[1].Actual, ShouldEqual, "3")
+	})
+}
@@ -0,0 +1,196 @@
+package cloudcontrol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscloudcontrol "github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+type (
+	// API is the part of the Cloud Control client read by the handlers
+	API interface {
+		GetResource(ctx context.Context, params *awscloudcontrol.GetResourceInput, optFns ...func(*awscloudcontrol.Options)) (*awscloudcontrol.GetResourceOutput, error)
+	}
+
+	// Handler checks the drift of a resource type without a dedicated handler, the resources are read with the Cloud
+	// Control API and their properties compared through the mapping of the type. The differences are best effort
+	Handler struct {
+		client       API
+		resourceType string
+		mapping      *mapping
+	}
+)
+
+// NewHandlers creates the handlers of the mapped resource types, apart from the registered ones which have a
+// dedicated handler
+func NewHandlers(client API, registered []string) []registry.Handler {
+	resourceTypes := make([]string, 0, len(mappings))
+	for resourceType := range mappings {
+		if !slices.Contains(registered, resourceType) {
+			resourceTypes = append(resourceTypes, resourceType)
+		}
+	}
+	sort.Strings(resourceTypes)
+
+	handlers := make([]registry.Handler, 0, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		handlers = append(handlers, &Handler{client: client, resourceType: resourceType, mapping: mappings[resourceType]})
+	}
+	return handlers
+}
+
+func (h *Handler) Types() []string {
+	return []string{h.resourceType}
+}
+
+// Normalize decodes the resources of the handler type, identified by the primary identifier of their CloudFormation
+// type
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[h.resourceType]))
+	for _, stateResource := range state[h.resourceType] {
+		attributes := make(map[string]interface{})
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &attributes); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+
+		values := make(map[string]interface{}, len(attributes))
+		for key, value := range attributes {
+			values[key] = value
+		}
+		values["tags"] = map[string]string{}
+		if tags := utils.MergedTags(utils.StringMap(attributes["tags"]), utils.StringMap(attributes["tags_all"])); tags != nil {
+			values["tags"] = tags
+		}
+		resources = append(resources, &registry.Resource{
+			Type:    h.resourceType,
+			Address: stateResource.Address,
+			ID:      h.mapping.identifier(attributes),
+			Object:  attributes,
+			Values:  values,
+		})
+	}
+	return resources, nil
+}
+
+// Fetch gets the properties of the resources with Cloud Control, one call per resource. The values are keyed by
+// terraform path and only the mapped properties returned by Cloud Control are kept
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	awsResources := make(map[string]*registry.Resource, len(resources))
+	for _, resource := range resources {
+		id := resource.ID
+		output, err := h.client.GetResource(ctx, &awscloudcontrol.GetResourceInput{
+			TypeName:   aws.String(h.mapping.typeName),
+			Identifier: aws.String(id),
+		})
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			utils.Logger.Sugar().Errorf("failed to get %s %s from cloud control: %v", h.mapping.typeName, id, err)
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+			}
+		}
+
+		properties := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(aws.ToString(output.ResourceDescription.Properties)), &properties); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        fmt.Errorf("invalid properties of %s %s: %w", h.mapping.typeName, id, err),
+			}
+		}
+		attributes := h.mapping.attributesOf(resource.Object.(map[string]interface{}), properties)
+		values := make(map[string]interface{}, len(attributes)+1)
+		for attribute, property := range attributes {
+			if value, ok := valueAt(properties, property); ok {
+				values[attribute] = value
+			}
+		}
+		if tags, ok := properties["Tags"]; ok {
+			values["tags"] = tagMap(tags)
+		}
+		awsResources[id] = &registry.Resource{Type: h.resourceType, ID: id, Object: properties, Values: values}
+	}
+	return awsResources, nil
+}
+
+// Compare compares the mapped properties of a resource with the terraform state, every difference is marked best
+// effort as the mapping may not cover the defaults and formats of the type
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	tfValues := tfResource.Object.(map[string]interface{})
+	awsValues := awsResource.Values
+	keys := utils.SortedKeys(awsValues)
+
+	differences := make([]*entities.Difference, 0)
+	for _, key := range keys {
+		if key == "tags" {
+			awsTags, _ := awsValues[key].(map[string]string)
+			tags, tagsAll := utils.StringMap(tfValues["tags"]), utils.StringMap(tfValues["tags_all"])
+			differences = append(differences, utils.TagDifferences(awsTags, tags, tagsAll, options.IncludeReservedTags)...)
+			continue
+		}
+		tfValue, _ := valueAt(tfValues, key)
+		differences = append(differences, utils.CompareNested(key, awsValues[key], tfValue)...)
+	}
+	for _, difference := range differences {
+		difference.Confidence = entities.ConfidenceBestEffort
+	}
+	return differences, nil
+}
+
+// valueAt returns the value at a dotted path of nested objects, a segment may index a list, e.g.
+// point_in_time_recovery[0].enabled
+func valueAt(value interface{}, path string) (interface{}, bool) {
+	for _, segment := range strings.Split(path, ".") {
+		name, index, indexed := strings.Cut(segment, "[")
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[name]; !ok {
+			return nil, false
+		}
+		if indexed {
+			i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+			list, ok := value.([]interface{})
+			if err != nil || !ok || i >= len(list) {
+				return nil, false
+			}
+			value = list[i]
+		}
+	}
+	return value, true
+}
+
+// tagMap turns the CloudFormation Tags property, a list of Key and Value objects or a map for a few types, into the
+// terraform tags map
+func tagMap(value interface{}) map[string]string {
+	if object, ok := value.(map[string]interface{}); ok {
+		return utils.StringMap(object)
+	}
+	list, _ := value.([]interface{})
+	tags := make(map[string]string, len(list))
+	for _, element := range list {
+		tag, _ := element.(map[string]interface{})
+		tags[utils.StringValue(tag["Key"])] = utils.StringValue(tag["Value"])
+	}
+	return tags
+}
@@ -0,0 +1,167 @@
+package cloudcontrol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscloudcontrol "github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cftypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/registry/registrytest"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// cloudControlClient returns the properties of the resources it holds by type name and identifier
+type cloudControlClient struct {
+	properties map[string]string
+}
+
+func (c *cloudControlClient) GetResource(ctx context.Context, params *awscloudcontrol.GetResourceInput, optFns ...func(*awscloudcontrol.Options)) (*awscloudcontrol.GetResourceOutput, error) {
+	properties, ok := c.properties[aws.ToString(params.TypeName)+"|"+aws.ToString(params.Identifier)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Resource not found")}
+	}
+	return &awscloudcontrol.GetResourceOutput{
+		TypeName:            params.TypeName,
+		ResourceDescription: &types.ResourceDescription{Identifier: params.Identifier, Properties: aws.String(properties)},
+	}, nil
+}
+
+// typeRegistry lists the resource types it holds with their schema, per provisioning type
+type typeRegistry struct {
+	schemas map[cftypes.ProvisioningType]map[string]string
+}
+
+func (r *typeRegistry) ListTypes(ctx context.Context, params *cloudformation.ListTypesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListTypesOutput, error) {
+	output := &cloudformation.ListTypesOutput{}
+	for typeName := range r.schemas[params.ProvisioningType] {
+		output.TypeSummaries = append(output.TypeSummaries, cftypes.TypeSummary{TypeName: aws.String(typeName)})
+	}
+	return output, nil
+}
+
+func (r *typeRegistry) DescribeType(ctx context.Context, params *cloudformation.DescribeTypeInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeTypeOutput, error) {
+	for _, schemas := range r.schemas {
+		if schema, ok := schemas[aws.ToString(params.TypeName)]; ok {
+			return &cloudformation.DescribeTypeOutput{TypeName: params.TypeName, Schema: aws.String(schema)}, nil
+		}
+	}
+	return nil, &cftypes.TypeNotFoundException{Message: aws.String("Type not found")}
+}
+
+func TestCloudControlHandlers(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	state, err := registry.LoadState("../../testdata/cloudcontrol.tfstate.json")
+	client := &cloudControlClient{properties: map[string]string{
+		"AWS::SQS::Queue|https://sqs.us-west-2.amazonaws.com/123456789012/orders": `{
+			"QueueUrl": "https://sqs.us-west-2.amazonaws.com/123456789012/orders",
+			"QueueName": "orders",
+			"DelaySeconds": 0,
+			"MaximumMessageSize": 262144,
+			"MessageRetentionPeriod": 345600,
+			"ReceiveMessageWaitTimeSeconds": 20,
+			"SqsManagedSseEnabled": true,
+			"VisibilityTimeout": 120,
+			"Tags": [{"Key": "Team", "Value": "payments"}]
+		}`,
+		"AWS::SecretsManager::Secret|arn:aws:secretsmanager:us-west-2:123456789012:secret:api-key-AbCdEf": `{
+			"Id": "arn:aws:secretsmanager:us-west-2:123456789012:secret:api-key-AbCdEf",
+			"Name": "api-key",
+			"Description": "api key of the partners",
+			"ReplicaRegions": [],
+			"Tags": [{"Key": "Team", "Value": "api"}]
+		}`,
+		"AWS::ECR::Repository|api": `{
+			"RepositoryName": "api",
+			"ImageTagMutability": "MUTABLE",
+			"ImageScanningConfiguration": {"ScanOnPush": true},
+			"EncryptionConfiguration": {"EncryptionType": "AES256"}
+		}`,
+	}}
+	handlers := registry.New(NewHandlers(client, nil)...)
+
+	Convey("the mapped properties returned by cloud control are compared as best effort", t, func() {
+		So(err, ShouldBeNil)
+		results, err := registrytest.Check(handlers, "aws_sqs_queue", state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_sqs_queue.orders"].Differences
+		So(differences, ShouldHaveLength, 2)
+		So(differences[0].Attribute, ShouldEqual, "tags.Team")
+		So(differences[1].Attribute, ShouldEqual, "visibility_timeout_seconds")
+		So(differences[1].Expected, ShouldEqual, "30")
+		So(differences[1].Actual, ShouldEqual, "120")
+		for _, difference := range differences {
+			So(difference.Confidence, ShouldEqual, entities.ConfidenceBestEffort)
+		}
+	})
+
+	Convey("nested properties are mapped to the terraform blocks", t, func() {
+		results, err := registrytest.Check(handlers, "aws_ecr_repository", state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_ecr_repository.api"].Differences
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "image_tag_mutability")
+		So(differences[0].Actual, ShouldEqual, "MUTABLE")
+	})
+
+	Convey("a resource not found by cloud control is removed", t, func() {
+		results, err := registrytest.Check(handlers, "aws_dynamodb_table", state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(results["aws_dynamodb_table.sessions"].Removed, ShouldBeTrue)
+	})
+
+	Convey("the types with a dedicated handler are not handled by cloud control", t, func() {
+		types := registry.New(NewHandlers(client, []string{"aws_sqs_queue"})...).Types()
+		So(types, ShouldNotContain, "aws_sqs_queue")
+		So(types, ShouldContain, "aws_sns_topic")
+	})
+
+	Convey("the types without a mapping are resolved from the cloudformation registry", t, func() {
+		r := registry.New(NewHandlers(client, nil)...)
+		r.SetResolver(NewResolver(client, &typeRegistry{schemas: map[cftypes.ProvisioningType]map[string]string{
+			cftypes.ProvisioningTypeFullyMutable: {
+				"AWS::SecretsManager::Secret": `{"primaryIdentifier": ["/properties/Id"]}`,
+			},
+			cftypes.ProvisioningTypeImmutable: {
+				"AWS::SecretsManager::SecretTargetAttachment": `{"primaryIdentifier": ["/properties/Id"]}`,
+			},
+		}}))
+		handlers, err := r.Resolve(context.Background(), []string{"aws_secretsmanager_secret", "aws_sfn_state_machine", "random_id"})
+		So(err, ShouldBeNil)
+		So(handlers, ShouldHaveLength, 1)
+		So(handlers[0].Types(), ShouldResemble, []string{"aws_secretsmanager_secret"})
+
+		results, err := registrytest.Check(r, "aws_secretsmanager_secret", state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_secretsmanager_secret.api_key"].Differences
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "description")
+		So(differences[0].Expected, ShouldEqual, "api key")
+		So(differences[0].Actual, ShouldEqual, "api key of the partners")
+		So(differences[0].Confidence, ShouldEqual, entities.ConfidenceBestEffort)
+	})
+
+	Convey("the properties are named like the terraform attributes", t, func() {
+		So(snakeCase("KmsKeyId"), ShouldEqual, "kms_key_id")
+		So(snakeCase("VPCId"), ShouldEqual, "vpc_id")
+		So(snakeCase("Ipv6CidrBlock"), ShouldEqual, "ipv6_cidr_block")
+		So(typeKey("secretsmanager_secret"), ShouldEqual, typeKey("SecretsManager::Secret"))
+	})
+
+	Convey("paths index the nested lists", t, func() {
+		value, ok := valueAt(map[string]interface{}{
+			"point_in_time_recovery": []interface{}{map[string]interface{}{"enabled": true}},
+		}, "point_in_time_recovery[0].enabled")
+		So(ok, ShouldBeTrue)
+		So(value, ShouldEqual, true)
+		_, ok = valueAt(map[string]interface{}{"point_in_time_recovery": []interface{}{}}, "point_in_time_recovery[0].enabled")
+		So(ok, ShouldBeFalse)
+	})
+}
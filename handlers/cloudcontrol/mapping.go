@@ -0,0 +1,147 @@
+package cloudcontrol
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/driftreport/utils"
+)
+
+// mapping maps a terraform resource type to its CloudFormation type. The attributes map terraform paths, e.g.
+// image_scanning_configuration[0].scan_on_push, to the path of the CloudFormation property, e.g.
+// ImageScanningConfiguration.ScanOnPush. Without attributes, the top level scalar properties are compared with the
+// state attributes of the same name in snake case. The Tags property is compared with the tags when the type has it
+type mapping struct {
+	typeName string
+	// idAttributes are the state attributes holding the primary identifier of the CloudFormation type, joined with |
+	// when the identifier is compound
+	idAttributes []string
+	attributes   map[string]string
+}
+
+// mappings are the resource types whose mapping is written by hand, the other types are resolved from the
+// CloudFormation registry by the Resolver. A type is mapped when its CloudFormation name cannot be derived from the
+// terraform one, e.g. aws_cloudwatch_log_group and AWS::Logs::LogGroup, or to compare its nested properties.
+// Attributes whose terraform and CloudFormation shapes differ, e.g. policies that are json strings in the state and
+// objects in CloudFormation, are left out
+var mappings = map[string]*mapping{
+	"aws_sqs_queue": {
+		typeName:     "AWS::SQS::Queue",
+		idAttributes: []string{"id"},
+		attributes: map[string]string{
+			"content_based_deduplication": "ContentBasedDeduplication",
+			"delay_seconds":               "DelaySeconds",
+			"fifo_queue":                  "FifoQueue",
+			"kms_master_key_id":           "KmsMasterKeyId",
+			"max_message_size":            "MaximumMessageSize",
+			"message_retention_seconds":   "MessageRetentionPeriod",
+			"receive_wait_time_seconds":   "ReceiveMessageWaitTimeSeconds",
+			"sqs_managed_sse_enabled":     "SqsManagedSseEnabled",
+			"visibility_timeout_seconds":  "VisibilityTimeout",
+		},
+	},
+	"aws_sns_topic": {
+		typeName:     "AWS::SNS::Topic",
+		idAttributes: []string{"arn"},
+		attributes: map[string]string{
+			"content_based_deduplication": "ContentBasedDeduplication",
+			"display_name":                "DisplayName",
+			"fifo_topic":                  "FifoTopic",
+			"kms_master_key_id":           "KmsMasterKeyId",
+		},
+	},
+	"aws_dynamodb_table": {
+		typeName:     "AWS::DynamoDB::Table",
+		idAttributes: []string{"name"},
+		attributes: map[string]string{
+			"billing_mode":                      "BillingMode",
+			"deletion_protection_enabled":       "DeletionProtectionEnabled",
+			"point_in_time_recovery[0].enabled": "PointInTimeRecoverySpecification.PointInTimeRecoveryEnabled",
+			"read_capacity":                     "ProvisionedThroughput.ReadCapacityUnits",
+			"stream_view_type":                  "StreamSpecification.StreamViewType",
+			"table_class":                       "TableClass",
+			"write_capacity":                    "ProvisionedThroughput.WriteCapacityUnits",
+		},
+	},
+	"aws_cloudwatch_log_group": {
+		typeName:     "AWS::Logs::LogGroup",
+		idAttributes: []string{"name"},
+		attributes: map[string]string{
+			"kms_key_id":        "KmsKeyId",
+			"log_group_class":   "LogGroupClass",
+			"retention_in_days": "RetentionInDays",
+		},
+	},
+	"aws_kms_key": {
+		typeName:     "AWS::KMS::Key",
+		idAttributes: []string{"key_id"},
+		attributes: map[string]string{
+			"customer_master_key_spec": "KeySpec",
+			"description":              "Description",
+			"enable_key_rotation":      "EnableKeyRotation",
+			"key_usage":                "KeyUsage",
+			"multi_region":             "MultiRegion",
+		},
+	},
+	"aws_ecr_repository": {
+		typeName:     "AWS::ECR::Repository",
+		idAttributes: []string{"name"},
+		attributes: map[string]string{
+			"encryption_configuration[0].encryption_type":  "EncryptionConfiguration.EncryptionType",
+			"image_scanning_configuration[0].scan_on_push": "ImageScanningConfiguration.ScanOnPush",
+			"image_tag_mutability":                         "ImageTagMutability",
+		},
+	},
+}
+
+// identifier returns the primary identifier of a resource from its state attributes. A resolved type falls back to the
+// terraform id when the state has no attribute named after an identifier property
+func (m *mapping) identifier(attributes map[string]interface{}) string {
+	parts := make([]string, 0, len(m.idAttributes))
+	for _, attribute := range m.idAttributes {
+		part := utils.StringValue(attributes[attribute])
+		if part == "" {
+			return utils.StringValue(attributes["id"])
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "|")
+}
+
+// attributesOf returns the attributes compared for a resource keyed by terraform path. Without mapped attributes,
+// they are the top level scalar properties whose snake case name is a state attribute, e.g. KmsKeyId and kms_key_id
+func (m *mapping) attributesOf(tfAttributes, properties map[string]interface{}) map[string]string {
+	if m.attributes != nil {
+		return m.attributes
+	}
+	attributes := make(map[string]string)
+	for property, value := range properties {
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			continue
+		}
+		if attribute := snakeCase(property); attribute != "tags" {
+			if _, ok := tfAttributes[attribute]; ok {
+				attributes[attribute] = property
+			}
+		}
+	}
+	return attributes
+}
+
+// snakeCase turns a CloudFormation property name into the terraform attribute name, e.g. VPCId into vpc_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package cloudcontrol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cftypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+type (
+	// TypeAPI is the part of the CloudFormation client describing the resource types of the registry
+	TypeAPI interface {
+		ListTypes(ctx context.Context, params *cloudformation.ListTypesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListTypesOutput, error)
+		DescribeType(ctx context.Context, params *cloudformation.DescribeTypeInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeTypeOutput, error)
+	}
+
+	// Resolver creates the Cloud Control handlers of the aws resource types of the state without a handler. The
+	// CloudFormation type of a terraform type is the AWS type of the same name, e.g. aws_secretsmanager_secret and
+	// AWS::SecretsManager::Secret, among the types Cloud Control can read
+	Resolver struct {
+		client API
+		types  TypeAPI
+	}
+)
+
+// NewResolver creates the resolver of the resource types without a handler
+func NewResolver(client API, types TypeAPI) *Resolver {
+	return &Resolver{client: client, types: types}
+}
+
+// Resolve lists the AWS types of the CloudFormation registry once and creates a handler for each resource type
+// matching one of them, identified by the primaryIdentifier of its schema. The other types are left out
+func (r *Resolver) Resolve(ctx context.Context, resourceTypes []string) ([]registry.Handler, error) {
+	// the registry of the zero clients only lists the supported types
+	if r.types == nil {
+		return nil, nil
+	}
+	unresolved := make([]string, 0, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		if strings.HasPrefix(resourceType, "aws_") {
+			unresolved = append(unresolved, resourceType)
+		}
+	}
+	if len(unresolved) == 0 {
+		return nil, nil
+	}
+	sort.Strings(unresolved)
+
+	typeNames, err := r.typeNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	handlers := make([]registry.Handler, 0, len(unresolved))
+	for _, resourceType := range unresolved {
+		typeName, ok := typeNames[typeKey(strings.TrimPrefix(resourceType, "aws_"))]
+		if !ok {
+			continue
+		}
+		idAttributes, err := r.idAttributes(ctx, typeName)
+		if err != nil {
+			return nil, err
+		}
+		handlers = append(handlers, &Handler{
+			client:       r.client,
+			resourceType: resourceType,
+			mapping:      &mapping{typeName: typeName, idAttributes: idAttributes},
+		})
+	}
+	return handlers, nil
+}
+
+// typeNames returns the AWS types Cloud Control can read keyed by typeKey, a key matching several types is left out
+func (r *Resolver) typeNames(ctx context.Context) (map[string]string, error) {
+	typeNames := make(map[string]string)
+	ambiguous := make(map[string]bool)
+	// the non provisionable types have no read handler
+	for _, provisioningType := range []cftypes.ProvisioningType{cftypes.ProvisioningTypeFullyMutable, cftypes.ProvisioningTypeImmutable} {
+		paginator := cloudformation.NewListTypesPaginator(r.types, &cloudformation.ListTypesInput{
+			Visibility:       cftypes.VisibilityPublic,
+			Type:             cftypes.RegistryTypeResource,
+			ProvisioningType: provisioningType,
+			DeprecatedStatus: cftypes.DeprecatedStatusLive,
+			Filters:          &cftypes.TypeFilters{Category: cftypes.CategoryAwsTypes},
+		})
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				utils.Logger.Sugar().Errorf("failed to list the cloudformation resource types: %v", err)
+				return nil, &entities.CustomError{
+					StatusCode: http.StatusBadRequest,
+					Err:        err,
+				}
+			}
+			for _, summary := range output.TypeSummaries {
+				typeName := aws.ToString(summary.TypeName)
+				key := typeKey(strings.TrimPrefix(typeName, "AWS::"))
+				if existing, ok := typeNames[key]; ok && existing != typeName {
+					ambiguous[key] = true
+				}
+				typeNames[key] = typeName
+			}
+		}
+	}
+	for key := range ambiguous {
+		delete(typeNames, key)
+	}
+	return typeNames, nil
+}
+
+// idAttributes returns the state attributes of the primary identifier properties of a type, e.g. KeyId and key_id
+func (r *Resolver) idAttributes(ctx context.Context, typeName string) ([]string, error) {
+	output, err := r.types.DescribeType(ctx, &cloudformation.DescribeTypeInput{
+		Type:     cftypes.RegistryTypeResource,
+		TypeName: aws.String(typeName),
+	})
+	if err != nil {
+		utils.Logger.Sugar().Errorf("failed to describe the cloudformation type %s: %v", typeName, err)
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+	}
+
+	var schema struct {
+		PrimaryIdentifier []string `json:"primaryIdentifier"`
+	}
+	if err := json.Unmarshal([]byte(aws.ToString(output.Schema)), &schema); err != nil {
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        fmt.Errorf("invalid schema of %s: %w", typeName, err),
+		}
+	}
+	idAttributes := make([]string, 0, len(schema.PrimaryIdentifier))
+	for _, pointer := range schema.PrimaryIdentifier {
+		idAttributes = append(idAttributes, snakeCase(strings.TrimPrefix(pointer, "/properties/")))
+	}
+	return idAttributes, nil
+}
+
+// typeKey is the name of a type without separators and case, e.g. secretsmanagersecret for both
+// secretsmanager_secret and SecretsManager::Secret
+func typeKey(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "::", "").Replace(name))
+}
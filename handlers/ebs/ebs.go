@@ -0,0 +1,192 @@
+package ebs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const (
+	ResourceType           = "aws_ebs_volume"
+	AttachmentResourceType = "aws_volume_attachment"
+)
+
+// Handler checks the drift of aws_ebs_volume and aws_volume_attachment resources, the attachments are checked
+// against the attachments of their volume
+type Handler struct {
+	awsProvider providers.AWSProvider
+}
+
+func NewHandler(awsProvider providers.AWSProvider) *Handler {
+	return &Handler{awsProvider: awsProvider}
+}
+
+func (h *Handler) Types() []string {
+	return []string{ResourceType, AttachmentResourceType}
+}
+
+// Normalize decodes the EBS volumes and volume attachments of the terraform state, the attachments are keyed by their
+// own id
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[ResourceType])+len(state[AttachmentResourceType]))
+	for _, stateResource := range state[ResourceType] {
+		var tfVolume entities.TerraformEBSVolume
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &tfVolume); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+		resources = append(resources, newVolumeResource(&entities.EBSVolume{
+			Address:          stateResource.Address,
+			VolumeID:         tfVolume.ID,
+			AvailabilityZone: tfVolume.AvailabilityZone,
+			Size:             tfVolume.Size,
+			Type:             tfVolume.Type,
+			Iops:             tfVolume.Iops,
+			Throughput:       tfVolume.Throughput,
+			Encrypted:        tfVolume.Encrypted,
+			KmsKeyID:         tfVolume.KmsKeyID,
+			Tags:             tfVolume.Tags,
+			TagsAll:          tfVolume.TagsAll,
+		}))
+	}
+	for _, stateResource := range state[AttachmentResourceType] {
+		attachment := &entities.VolumeAttachment{Address: stateResource.Address}
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, attachment); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+		resources = append(resources, &registry.Resource{
+			Type:    AttachmentResourceType,
+			Address: attachment.Address,
+			ID:      attachment.ID,
+			Object:  attachment,
+			Values:  map[string]interface{}{"instance_id": attachment.InstanceID, "device_name": attachment.DeviceName, "volume_id": attachment.VolumeID},
+		})
+	}
+	return resources, nil
+}
+
+// Fetch gets the volumes, and the volumes of the attachments, from AWS. An attachment gets the AWS volume it is
+// attached to, with the attachment seen from AWS as values
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	volumeIds := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		if attachment, ok := resource.Object.(*entities.VolumeAttachment); ok {
+			volumeIds = utils.AppendUnique(volumeIds, attachment.VolumeID)
+		} else {
+			volumeIds = utils.AppendUnique(volumeIds, resource.ID)
+		}
+	}
+	volumeMap, err := h.awsProvider.GetEBSVolumes(ctx, volumeIds)
+	if err != nil {
+		return nil, err
+	}
+
+	awsResources := make(map[string]*registry.Resource, len(resources))
+	for _, resource := range resources {
+		attachment, ok := resource.Object.(*entities.VolumeAttachment)
+		if !ok {
+			if volume, found := volumeMap[resource.ID]; found {
+				awsResources[resource.ID] = newVolumeResource(volume)
+			}
+			continue
+		}
+		volume, found := volumeMap[attachment.VolumeID]
+		if !found {
+			continue
+		}
+		values := map[string]interface{}{"volume_id": volume.VolumeID, "instance_id": nil}
+		if device, attached := volume.Attachments[attachment.InstanceID]; attached {
+			values["instance_id"] = attachment.InstanceID
+			values["device_name"] = device
+		} else if instanceIDs := attachedInstances(volume); len(instanceIDs) > 0 {
+			values["instance_id"] = strings.Join(instanceIDs, ",")
+		}
+		awsResources[resource.ID] = &registry.Resource{
+			Type:   AttachmentResourceType,
+			ID:     resource.ID,
+			Object: volume,
+			Values: values,
+		}
+	}
+	return awsResources, nil
+}
+
+// Compare compares an EBS volume from AWS with the terraform state, or an aws_volume_attachment with the attachments
+// of its volume
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	if tfResource.Type == AttachmentResourceType {
+		return compareAttachment(tfResource, awsResource)
+	}
+
+	tfVolume, awsVolume := tfResource.Object.(*entities.EBSVolume), awsResource.Object.(*entities.EBSVolume)
+	differences := make([]*entities.Difference, 0)
+	for _, attribute := range []string{"availability_zone", "size", "type", "iops", "throughput", "encrypted", "kms_key_id"} {
+		if tfResource.Values[attribute] != awsResource.Values[attribute] {
+			differences = append(differences, entities.NewDifference(attribute, tfResource.Values[attribute], awsResource.Values[attribute]))
+		}
+	}
+	differences = append(differences, utils.TagDifferences(awsVolume.Tags, tfVolume.Tags, tfVolume.TagsAll, options.IncludeReservedTags)...)
+	return differences, nil
+}
+
+// compareAttachment compares the instance and device of an attachment with the attachments of the volume in AWS
+func compareAttachment(tfResource, awsResource *registry.Resource) ([]*entities.Difference, error) {
+	attachment := tfResource.Object.(*entities.VolumeAttachment)
+	differences := make([]*entities.Difference, 0)
+	if device, ok := awsResource.Values["device_name"]; ok {
+		if device != attachment.DeviceName {
+			differences = append(differences, entities.NewDifference("device_name", attachment.DeviceName, device))
+		}
+	} else {
+		// the volume is detached or attached to other instances
+		differences = append(differences, entities.NewDifference("instance_id", attachment.InstanceID, awsResource.Values["instance_id"]))
+	}
+	return differences, nil
+}
+
+// attachedInstances returns the ids of the instances a volume is attached to in order, so that the instance_id of a
+// multi-attached volume is the same from run to run
+func attachedInstances(volume *entities.EBSVolume) []string {
+	instanceIDs := make([]string, 0, len(volume.Attachments))
+	for instanceID := range volume.Attachments {
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+	sort.Strings(instanceIDs)
+	return instanceIDs
+}
+
+// newVolumeResource wraps a volume of either side, its values are compared and given to the custom rules
+func newVolumeResource(volume *entities.EBSVolume) *registry.Resource {
+	tags := utils.MergedTags(volume.Tags, volume.TagsAll)
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	return &registry.Resource{
+		Type:    ResourceType,
+		Address: volume.Address,
+		ID:      volume.VolumeID,
+		Object:  volume,
+		Values: map[string]interface{}{
+			"availability_zone": volume.AvailabilityZone,
+			"size":              volume.Size,
+			"type":              volume.Type,
+			"iops":              volume.Iops,
+			"throughput":        volume.Throughput,
+			"encrypted":         volume.Encrypted,
+			"kms_key_id":        volume.KmsKeyID,
+			"tags":              tags,
+		},
+	}
+}
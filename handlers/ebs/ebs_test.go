@@ -0,0 +1,100 @@
+package ebs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// volumeProvider returns the same volumes for every call
+type volumeProvider struct {
+	providers.AWSProvider
+	volumes map[string]*entities.EBSVolume
+}
+
+func (p *volumeProvider) GetEBSVolumes(ctx context.Context, volumeIDs []string) (map[string]*entities.EBSVolume, error) {
+	return p.volumes, nil
+}
+
+func TestEBSHandler(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	awsVolume := &entities.EBSVolume{
+		VolumeID:         "vol-0a1b2c3d4e5f60789",
+		AvailabilityZone: "us-west-2a",
+		Size:             50,
+		Type:             "gp3",
+		Iops:             3000,
+		Throughput:       250,
+		Encrypted:        true,
+		KmsKeyID:         "arn:aws:kms:us-west-2:484224457871:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+		Tags:             map[string]string{"Name": "data"},
+		Attachments:      map[string]string{"i-0c568478aa8a54807": "/dev/sdg"},
+	}
+	handler := NewHandler(&volumeProvider{volumes: map[string]*entities.EBSVolume{awsVolume.VolumeID: awsVolume}})
+	state, err := registry.LoadState("../../testdata/ebs_volumes.tfstate.json")
+	resources, normalizeErr := handler.Normalize(state)
+	tfResources := make(map[string]*registry.Resource)
+	for _, resource := range resources {
+		tfResources[resource.ID] = resource
+	}
+
+	Convey("load volumes and attachments from the terraform state", t, func() {
+		So(err, ShouldBeNil)
+		So(normalizeErr, ShouldBeNil)
+		So(tfResources, ShouldContainKey, "vol-0a1b2c3d4e5f60789")
+		So(tfResources["vol-0a1b2c3d4e5f60789"].Address, ShouldEqual, "aws_ebs_volume.data")
+		So(tfResources["vai-3920456789"].Object.(*entities.VolumeAttachment).InstanceID, ShouldEqual, "i-0c568478aa8a54807")
+	})
+
+	Convey("resized volume is reported as drift", t, func() {
+		awsResources, err := handler.Fetch(context.Background(), resources)
+		So(err, ShouldBeNil)
+		differences, err := handler.Compare(tfResources["vol-0a1b2c3d4e5f60789"], awsResources["vol-0a1b2c3d4e5f60789"], &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differenceDetails(differences), ShouldResemble, map[string]string{
+			"size":       "AWS: 50, Terraform: 20",
+			"throughput": "AWS: 250, Terraform: 125",
+		})
+	})
+
+	Convey("attachment device and instance are compared", t, func() {
+		awsResources, err := handler.Fetch(context.Background(), resources)
+		So(err, ShouldBeNil)
+		differences, err := handler.Compare(tfResources["vai-3920456789"], awsResources["vai-3920456789"], &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differenceDetails(differences), ShouldResemble, map[string]string{
+			"device_name": "AWS: /dev/sdg, Terraform: /dev/sdf",
+		})
+
+		awsVolume.Attachments = map[string]string{}
+		awsResources, err = handler.Fetch(context.Background(), resources)
+		So(err, ShouldBeNil)
+		differences, err = handler.Compare(tfResources["vai-3920456789"], awsResources["vai-3920456789"], &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differences[0].Attribute, ShouldEqual, "instance_id")
+		So(differences[0].Kind, ShouldEqual, entities.ChangeKindRemoved)
+
+		awsVolume.Attachments = map[string]string{"i-0f": "/dev/sdf", "i-0a": "/dev/sdf", "i-0c": "/dev/sdf"}
+		for i := 0; i < 10; i++ {
+			awsResources, err = handler.Fetch(context.Background(), resources)
+			So(err, ShouldBeNil)
+			So(awsResources["vai-3920456789"].Values["instance_id"], ShouldEqual, "i-0a,i-0c,i-0f")
+		}
+	})
+}
+
+// differenceDetails maps the attribute paths of the differences to their printed form
+func differenceDetails(differences []*entities.Difference) map[string]string {
+	details := make(map[string]string)
+	for _, difference := range differences {
+		details[difference.Attribute] = difference.String()
+	}
+	return details
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"github.com/driftreport/handlers/autoscaling"
+	"github.com/driftreport/handlers/cloudcontrol"
+	"github.com/driftreport/handlers/ebs"
+	"github.com/driftreport/handlers/iam"
+	"github.com/driftreport/handlers/instance"
+	"github.com/driftreport/handlers/lambda"
+	"github.com/driftreport/handlers/loadbalancer"
+	"github.com/driftreport/handlers/network"
+	"github.com/driftreport/handlers/rds"
+	"github.com/driftreport/handlers/route53"
+	"github.com/driftreport/handlers/s3bucket"
+	"github.com/driftreport/handlers/securitygroup"
+	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+)
+
+// Clients are the AWS clients the handlers read the resources with, a zero value is enough to list the supported
+// resource types
+type Clients struct {
+	AWSProvider  providers.AWSProvider
+	S3           s3bucket.API
+	IAM          iam.API
+	AutoScaling  autoscaling.API
+	RDS          rds.API
+	ELB          loadbalancer.API
+	Lambda       lambda.API
+	Route53      route53.API
+	CloudControl cloudcontrol.API
+	// CloudFormation resolves the types read with Cloud Control without a mapping
+	CloudFormation cloudcontrol.TypeAPI
+
+	// CheckASGInstances makes the auto scaling group handler read and check the instances of the groups
+	CheckASGInstances bool
+}
+
+// NewRegistry registers the handlers of every supported resource type, a new resource type is added with its own
+// handler package registered here
+func NewRegistry(clients Clients) *registry.Registry {
+	r := registry.New(
+		instance.NewHandler(clients.AWSProvider),
+		securitygroup.NewHandler(clients.AWSProvider),
+		ebs.NewHandler(clients.AWSProvider),
+	)
+	for _, handler := range network.NewHandlers(clients.AWSProvider) {
+		r.Register(handler)
+	}
+	for _, handler := range s3bucket.NewHandlers(clients.S3) {
+		r.Register(handler)
+	}
+	for _, handler := range iam.NewHandlers(clients.IAM) {
+		r.Register(handler)
+	}
+	for _, handler := range autoscaling.NewHandlers(clients.AutoScaling, clients.AWSProvider, clients.CheckASGInstances) {
+		r.Register(handler)
+	}
+	for _, handler := range rds.NewHandlers(clients.RDS) {
+		r.Register(handler)
+	}
+	for _, handler := range loadbalancer.NewHandlers(clients.ELB) {
+		r.Register(handler)
+	}
+	r.Register(lambda.NewHandler(clients.Lambda))
+	r.Register(route53.NewHandler(clients.Route53))
+	// the Cloud Control handlers are the fallback of the mapped resource types, so they are registered last, and of
+	// the other types of the state once they are resolved
+	for _, handler := range cloudcontrol.NewHandlers(clients.CloudControl, r.Types()) {
+		r.Register(handler)
+	}
+	r.SetResolver(cloudcontrol.NewResolver(clients.CloudControl, clients.CloudFormation))
+	return r
+}
@@ -0,0 +1,263 @@
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsiam "github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const (
+	ResourceTypeRole             = "aws_iam_role"
+	ResourceTypePolicy           = "aws_iam_policy"
+	ResourceTypePolicyAttachment = "aws_iam_role_policy_attachment"
+	ResourceTypeRolePolicy       = "aws_iam_role_policy"
+)
+
+// policyDocuments are the attributes holding a policy document, they are compared semantically
+var policyDocuments = []string{"assume_role_policy", "policy"}
+
+type (
+	// API is the part of the IAM client read by the handlers
+	API interface {
+		GetRole(ctx context.Context, params *awsiam.GetRoleInput, optFns ...func(*awsiam.Options)) (*awsiam.GetRoleOutput, error)
+		GetPolicy(ctx context.Context, params *awsiam.GetPolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.GetPolicyOutput, error)
+		GetPolicyVersion(ctx context.Context, params *awsiam.GetPolicyVersionInput, optFns ...func(*awsiam.Options)) (*awsiam.GetPolicyVersionOutput, error)
+		ListAttachedRolePolicies(ctx context.Context, params *awsiam.ListAttachedRolePoliciesInput, optFns ...func(*awsiam.Options)) (*awsiam.ListAttachedRolePoliciesOutput, error)
+		GetRolePolicy(ctx context.Context, params *awsiam.GetRolePolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.GetRolePolicyOutput, error)
+	}
+
+	// Handler checks the drift of an IAM resource type, its resources are compared attribute by attribute with the
+	// state and its policy documents by their canonical form
+	Handler struct {
+		client       API
+		resourceType string
+		id           identifier
+		read         reader
+	}
+
+	// identifier returns the id of a resource from its state attributes, the id the resource is read from IAM with
+	identifier func(attributes map[string]interface{}) string
+
+	// reader reads a resource from IAM in its terraform shape, or nil when it does not exist any more
+	reader func(ctx context.Context, client API, attributes map[string]interface{}) (map[string]interface{}, error)
+)
+
+// NewHandlers creates the handlers of the IAM resource types
+func NewHandlers(client API) []registry.Handler {
+	return []registry.Handler{
+		&Handler{client: client, resourceType: ResourceTypeRole, id: attribute("name"), read: readRole},
+		&Handler{client: client, resourceType: ResourceTypePolicy, id: attribute("arn"), read: readPolicy},
+		&Handler{client: client, resourceType: ResourceTypePolicyAttachment, id: attachmentID, read: readPolicyAttachment},
+		&Handler{client: client, resourceType: ResourceTypeRolePolicy, id: rolePolicyID, read: readRolePolicy},
+	}
+}
+
+func (h *Handler) Types() []string {
+	return []string{h.resourceType}
+}
+
+// Normalize decodes the resources of the handler type
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[h.resourceType]))
+	for _, stateResource := range state[h.resourceType] {
+		attributes := make(map[string]interface{})
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &attributes); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+
+		values := make(map[string]interface{}, len(attributes))
+		for key, value := range attributes {
+			values[key] = value
+		}
+		if _, ok := attributes["tags"]; ok {
+			values["tags"] = map[string]string{}
+			if tags := utils.MergedTags(utils.StringMap(attributes["tags"]), utils.StringMap(attributes["tags_all"])); tags != nil {
+				values["tags"] = tags
+			}
+		}
+		resources = append(resources, &registry.Resource{
+			Type:    h.resourceType,
+			Address: stateResource.Address,
+			ID:      h.id(attributes),
+			Object:  attributes,
+			Values:  values,
+		})
+	}
+	return resources, nil
+}
+
+// Fetch reads the resources from IAM in their terraform shape, the resources that do not exist any more are left out
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	awsResources := make(map[string]*registry.Resource, len(resources))
+	for _, resource := range resources {
+		values, err := h.read(ctx, h.client, resource.Object.(map[string]interface{}))
+		if err != nil {
+			utils.Logger.Sugar().Errorf("failed to read %s %s: %v", h.resourceType, resource.ID, err)
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        fmt.Errorf("%s %s: %w", h.resourceType, resource.ID, err),
+			}
+		}
+		if values == nil {
+			continue
+		}
+		awsResources[resource.ID] = &registry.Resource{Type: h.resourceType, ID: resource.ID, Object: values, Values: values}
+	}
+	return awsResources, nil
+}
+
+// Compare compares an IAM resource with the terraform state. Only the attributes read from IAM are compared, policy
+// documents are compared semantically so that the encoding, formatting and order of their statements are not reported
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	tfValues := tfResource.Object.(map[string]interface{})
+	awsValues := awsResource.Values
+	keys := utils.SortedKeys(awsValues)
+
+	differences := make([]*entities.Difference, 0)
+	for _, key := range keys {
+		switch {
+		case key == "tags":
+			awsTags, _ := awsValues[key].(map[string]string)
+			tags, tagsAll := utils.StringMap(tfValues["tags"]), utils.StringMap(tfValues["tags_all"])
+			differences = append(differences, utils.TagDifferences(awsTags, tags, tagsAll, options.IncludeReservedTags)...)
+		case slices.Contains(policyDocuments, key):
+			difference, err := utils.PolicyDifference(key, utils.StringValue(tfValues[key]), utils.StringValue(awsValues[key]))
+			if err != nil {
+				return nil, err
+			}
+			if difference != nil {
+				differences = append(differences, difference)
+			}
+		default:
+			differences = append(differences, utils.CompareNested(key, awsValues[key], tfValues[key])...)
+		}
+	}
+	return differences, nil
+}
+
+func readRole(ctx context.Context, client API, attributes map[string]interface{}) (map[string]interface{}, error) {
+	output, err := client.GetRole(ctx, &awsiam.GetRoleInput{RoleName: aws.String(utils.StringValue(attributes["name"]))})
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role := output.Role
+	permissionsBoundary := ""
+	if role.PermissionsBoundary != nil {
+		permissionsBoundary = aws.ToString(role.PermissionsBoundary.PermissionsBoundaryArn)
+	}
+	return map[string]interface{}{
+		"assume_role_policy":   aws.ToString(role.AssumeRolePolicyDocument),
+		"description":          aws.ToString(role.Description),
+		"max_session_duration": aws.ToInt32(role.MaxSessionDuration),
+		"path":                 aws.ToString(role.Path),
+		"permissions_boundary": permissionsBoundary,
+		"tags":                 tagMap(role.Tags),
+	}, nil
+}
+
+// readPolicy reads a managed policy and the document of its default version
+func readPolicy(ctx context.Context, client API, attributes map[string]interface{}) (map[string]interface{}, error) {
+	arn := aws.String(utils.StringValue(attributes["arn"]))
+	output, err := client.GetPolicy(ctx, &awsiam.GetPolicyInput{PolicyArn: arn})
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := client.GetPolicyVersion(ctx, &awsiam.GetPolicyVersionInput{PolicyArn: arn, VersionId: output.Policy.DefaultVersionId})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"description": aws.ToString(output.Policy.Description),
+		"path":        aws.ToString(output.Policy.Path),
+		"policy":      aws.ToString(version.PolicyVersion.Document),
+		"tags":        tagMap(output.Policy.Tags),
+	}, nil
+}
+
+// readPolicyAttachment checks that the policy is attached to its role, a detached policy has no policy_arn
+func readPolicyAttachment(ctx context.Context, client API, attributes map[string]interface{}) (map[string]interface{}, error) {
+	role, policyArn := utils.StringValue(attributes["role"]), utils.StringValue(attributes["policy_arn"])
+	values := map[string]interface{}{"role": role, "policy_arn": nil}
+
+	paginator := awsiam.NewListAttachedRolePoliciesPaginator(client, &awsiam.ListAttachedRolePoliciesInput{RoleName: aws.String(role)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if isNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range page.AttachedPolicies {
+			if aws.ToString(policy.PolicyArn) == policyArn {
+				values["policy_arn"] = policyArn
+			}
+		}
+	}
+	return values, nil
+}
+
+func readRolePolicy(ctx context.Context, client API, attributes map[string]interface{}) (map[string]interface{}, error) {
+	output, err := client.GetRolePolicy(ctx, &awsiam.GetRolePolicyInput{
+		RoleName:   aws.String(utils.StringValue(attributes["role"])),
+		PolicyName: aws.String(utils.StringValue(attributes["name"])),
+	})
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"policy": aws.ToString(output.PolicyDocument)}, nil
+}
+
+func attribute(name string) identifier {
+	return func(attributes map[string]interface{}) string {
+		return utils.StringValue(attributes[name])
+	}
+}
+
+// attachmentID identifies a policy attachment by role and policy, the id terraform generates for it is not known to
+// IAM
+func attachmentID(attributes map[string]interface{}) string {
+	return utils.StringValue(attributes["role"]) + "/" + utils.StringValue(attributes["policy_arn"])
+}
+
+func rolePolicyID(attributes map[string]interface{}) string {
+	return utils.StringValue(attributes["role"]) + ":" + utils.StringValue(attributes["name"])
+}
+
+// tagMap turns the IAM tag list into the terraform tags map
+func tagMap(tags []types.Tag) map[string]string {
+	tagsMap := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		tagsMap[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tagsMap
+}
+
+func isNotFound(err error) bool {
+	var notFound *types.NoSuchEntityException
+	return errors.As(err, &notFound)
+}
@@ -0,0 +1,123 @@
+package iam
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsiam "github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/registry/registrytest"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// iamClient answers with a single role, its attached policies and inline policies, documents are URL-encoded like
+// the IAM API returns them
+type iamClient struct {
+	role             *types.Role
+	policy           *types.Policy
+	policyDocument   string
+	attachedPolicies []string
+	inlinePolicies   map[string]string
+}
+
+func (c *iamClient) GetRole(ctx context.Context, params *awsiam.GetRoleInput, optFns ...func(*awsiam.Options)) (*awsiam.GetRoleOutput, error) {
+	if aws.ToString(params.RoleName) != aws.ToString(c.role.RoleName) {
+		return nil, &types.NoSuchEntityException{}
+	}
+	return &awsiam.GetRoleOutput{Role: c.role}, nil
+}
+
+func (c *iamClient) GetPolicy(ctx context.Context, params *awsiam.GetPolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.GetPolicyOutput, error) {
+	return &awsiam.GetPolicyOutput{Policy: c.policy}, nil
+}
+
+func (c *iamClient) GetPolicyVersion(ctx context.Context, params *awsiam.GetPolicyVersionInput, optFns ...func(*awsiam.Options)) (*awsiam.GetPolicyVersionOutput, error) {
+	return &awsiam.GetPolicyVersionOutput{PolicyVersion: &types.PolicyVersion{
+		Document:  aws.String(url.QueryEscape(c.policyDocument)),
+		VersionId: params.VersionId,
+	}}, nil
+}
+
+func (c *iamClient) ListAttachedRolePolicies(ctx context.Context, params *awsiam.ListAttachedRolePoliciesInput, optFns ...func(*awsiam.Options)) (*awsiam.ListAttachedRolePoliciesOutput, error) {
+	output := &awsiam.ListAttachedRolePoliciesOutput{}
+	for _, arn := range c.attachedPolicies {
+		output.AttachedPolicies = append(output.AttachedPolicies, types.AttachedPolicy{PolicyArn: aws.String(arn)})
+	}
+	return output, nil
+}
+
+func (c *iamClient) GetRolePolicy(ctx context.Context, params *awsiam.GetRolePolicyInput, optFns ...func(*awsiam.Options)) (*awsiam.GetRolePolicyOutput, error) {
+	document, ok := c.inlinePolicies[aws.ToString(params.PolicyName)]
+	if !ok {
+		return nil, &types.NoSuchEntityException{}
+	}
+	return &awsiam.GetRolePolicyOutput{PolicyDocument: aws.String(url.QueryEscape(document))}, nil
+}
+
+func TestIAMHandlers(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	state, err := registry.LoadState("../../testdata/iam.tfstate.json")
+	client := &iamClient{
+		role: &types.Role{
+			RoleName:                 aws.String("app"),
+			AssumeRolePolicyDocument: aws.String(url.QueryEscape(`{"Version":"2012-10-17","Statement":{"Effect":"Allow","Principal":{"Service":["ec2.amazonaws.com"]},"Action":["sts:AssumeRole"]}}`)),
+			Description:              aws.String("application role"),
+			MaxSessionDuration:       aws.Int32(43200),
+			Path:                     aws.String("/"),
+			Tags:                     []types.Tag{{Key: aws.String("Name"), Value: aws.String("app")}},
+		},
+		policy: &types.Policy{
+			Arn:              aws.String("arn:aws:iam::123456789012:policy/app-logs"),
+			DefaultVersionId: aws.String("v2"),
+			Path:             aws.String("/"),
+		},
+		policyDocument: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["logs:CreateLogStream","logs:PutLogEvents","logs:DeleteLogGroup"],"Resource":"*"}]}`,
+		inlinePolicies: map[string]string{
+			"app-queue": `{"Statement":[{"Resource":"arn:aws:sqs:us-west-2:123456789012:events","Action":["sqs:SendMessage"],"Effect":"Allow"}],"Version":"2012-10-17"}`,
+		},
+	}
+
+	handlers := registry.New(NewHandlers(client)...)
+
+	Convey("an equivalent assume role policy is not reported, a changed session duration is", t, func() {
+		So(err, ShouldBeNil)
+		results, err := registrytest.Check(handlers, ResourceTypeRole, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_iam_role.app"].Differences
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "max_session_duration")
+		So(differences[0].Actual, ShouldEqual, "43200")
+	})
+
+	Convey("an action added to the default policy version is reported", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypePolicy, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_iam_policy.app_logs"].Differences
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "policy")
+		So(differences[0].Actual, ShouldContainSubstring, "logs:DeleteLogGroup")
+	})
+
+	Convey("a detached policy is reported as a removed policy_arn", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypePolicyAttachment, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_iam_role_policy_attachment.app_logs"].Differences
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "policy_arn")
+		So(differences[0].Kind, ShouldEqual, entities.ChangeKindRemoved)
+	})
+
+	Convey("an inline policy is compared semantically and a deleted one is removed", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypeRolePolicy, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(results["aws_iam_role_policy.app_queue"].Differences, ShouldBeEmpty)
+		So(results["aws_iam_role_policy.app_deleted"].Removed, ShouldBeTrue)
+	})
+}
@@ -0,0 +1,128 @@
+package instance
+
+import (
+	"context"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const ResourceType = "aws_instance"
+
+// nestedBlocks are the aws_instance blocks compared attribute by attribute with the AWS API data
+var nestedBlocks = []string{"root_block_device", "ebs_block_device", "metadata_options", "credit_specification", "network_interface"}
+
+// Handler checks the drift of aws_instance resources
+type Handler struct {
+	awsProvider providers.AWSProvider
+	attributes  map[string]bool
+}
+
+// NewHandler creates the aws_instance handler comparing the instance type, security groups, tags and nested blocks
+func NewHandler(awsProvider providers.AWSProvider) *Handler {
+	attributes := map[string]bool{"instance_type": true, "security_groups": true, "tags": true}
+	for _, block := range nestedBlocks {
+		attributes[block] = true
+	}
+	return &Handler{awsProvider: awsProvider, attributes: attributes}
+}
+
+func (h *Handler) Types() []string {
+	return []string{ResourceType}
+}
+
+// Normalize decodes the instances of the terraform state, the ebs_block_device and network_interface sets are sorted
+// by device name and index like the AWS side
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[ResourceType]))
+	for _, stateResource := range state[ResourceType] {
+		attrs := stateResource.Instance.Attributes
+		tfInstance := &entities.EC2Instance{
+			Address:        stateResource.Address,
+			InstanceType:   attrs.Type,
+			SecurityGroups: attrs.SecurityGroups,
+			Tags:           attrs.Tags,
+			TagsAll:        attrs.TagsAll,
+			Blocks: map[string]interface{}{
+				"root_block_device":    attrs.RootBlockDevice,
+				"ebs_block_device":     utils.SortBlocks(attrs.EBSBlockDevice, "device_name"),
+				"metadata_options":     attrs.MetadataOptions,
+				"credit_specification": attrs.CreditSpecification,
+				"network_interface":    utils.SortBlocks(attrs.NetworkInterface, "device_index"),
+			},
+		}
+		resources = append(resources, NewResource(attrs.InstanceID, tfInstance))
+	}
+	return resources, nil
+}
+
+// Fetch gets the instances from AWS EC2
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	ec2InstanceMap, err := h.awsProvider.GetEC2Instances(ctx, registry.IDs(resources))
+	if err != nil {
+		return nil, err
+	}
+	awsResources := make(map[string]*registry.Resource, len(ec2InstanceMap))
+	for id, ec2Instance := range ec2InstanceMap {
+		awsResources[id] = NewResource(id, ec2Instance)
+	}
+	return awsResources, nil
+}
+
+// Compare compares instance from AWS EC2 and terraform tfstate json file
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	tfInstance, ec2Instance := tfResource.Object.(*entities.EC2Instance), awsResource.Object.(*entities.EC2Instance)
+	differences := make([]*entities.Difference, 0)
+	if h.attributes["instance_type"] && ec2Instance.InstanceType != tfInstance.InstanceType {
+		differences = append(differences, entities.NewDifference("instance_type", tfInstance.InstanceType, ec2Instance.InstanceType))
+	}
+	if h.attributes["security_groups"] {
+		// security groups are compared one by one so that an extra group is reported as added
+		differences = append(differences, utils.SetDifferences("security_groups", ec2Instance.SecurityGroups, tfInstance.SecurityGroups)...)
+	}
+	if h.attributes["tags"] {
+		// tags are compared key by key so that ignore rules can target single tag keys
+		differences = append(differences, utils.TagDifferences(ec2Instance.Tags, tfInstance.Tags, tfInstance.TagsAll, options.IncludeReservedTags)...)
+	}
+
+	for _, block := range nestedBlocks {
+		// a block that is empty in the state is not managed inline by terraform, and a block missing on the AWS
+		// side was not read for this instance, neither can be compared
+		tfBlock, _ := tfInstance.Blocks[block].([]interface{})
+		if !h.attributes[block] || len(tfBlock) == 0 || ec2Instance.Blocks[block] == nil {
+			continue
+		}
+		differences = append(differences, utils.CompareNested(block, ec2Instance.Blocks[block], tfBlock)...)
+	}
+
+	return differences, nil
+}
+
+// NewResource wraps an instance of either side in a registry resource. Custom rules see the whole instance, tags are
+// given merged with the provider default_tags like AWS returns them and nested blocks are top level keys
+func NewResource(instanceID string, instance *entities.EC2Instance) *registry.Resource {
+	tags := utils.MergedTags(instance.Tags, instance.TagsAll)
+	values := map[string]interface{}{
+		"instance_type":   instance.InstanceType,
+		"security_groups": instance.SecurityGroups,
+		"tags":            tags,
+	}
+	if instance.SecurityGroups == nil {
+		values["security_groups"] = []string{}
+	}
+	if tags == nil {
+		values["tags"] = map[string]string{}
+	}
+	for block, value := range instance.Blocks {
+		values[block] = value
+	}
+	return &registry.Resource{
+		Type:    ResourceType,
+		Address: instance.Address,
+		ID:      instanceID,
+		Object:  instance,
+		Values:  values,
+	}
+}
@@ -0,0 +1,119 @@
+package instance
+
+import (
+	"testing"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInstanceHandler(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	handler := NewHandler(nil)
+	state, err := registry.LoadState("../../terraform.tfstate.json")
+
+	Convey("load terraform instances from terraform.tfstate.json file", t, func() {
+		So(err, ShouldBeNil)
+		resources, err := handler.Normalize(state)
+		So(err, ShouldBeNil)
+		So(len(resources), ShouldEqual, 1)
+		So(resources[0].Type, ShouldEqual, ResourceType)
+	})
+
+	Convey("tags_all and provider default tags", t, func() {
+		tfInstance := &entities.EC2Instance{
+			Address: "aws_instance.web",
+			Tags:    map[string]string{"Name": "web"},
+			TagsAll: map[string]string{"Name": "web", "Environment": "prod"},
+		}
+		ec2Instance := &entities.EC2Instance{
+			Tags: map[string]string{"Name": "web", "Environment": "prod", "aws:autoscaling:groupName": "web-asg"},
+		}
+		differences, err := handler.Compare(NewResource("i-1", tfInstance), NewResource("i-1", ec2Instance), &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differences, ShouldBeEmpty)
+
+		ec2Instance.Tags = map[string]string{"Name": "api", "Environment": "dev"}
+		differences, err = handler.Compare(NewResource("i-1", tfInstance), NewResource("i-1", ec2Instance), &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differenceDetails(differences), ShouldResemble, map[string]string{
+			"tags.Name":                "AWS: api, Terraform: web",
+			"default_tags.Environment": "AWS: dev, Terraform: prod",
+		})
+	})
+
+	Convey("nested blocks are compared with the state", t, func() {
+		resources, err := handler.Normalize(state)
+		So(err, ShouldBeNil)
+		tfInstance := resources[0].Object.(*entities.EC2Instance)
+
+		ec2Instance := &entities.EC2Instance{
+			InstanceType:   tfInstance.InstanceType,
+			SecurityGroups: tfInstance.SecurityGroups,
+			Tags:           tfInstance.Tags,
+			Blocks: map[string]interface{}{
+				"metadata_options": []interface{}{map[string]interface{}{
+					"http_endpoint": "enabled",
+					"http_tokens":   "required",
+				}},
+				"root_block_device": []interface{}{map[string]interface{}{
+					"volume_size": int32(8),
+					"volume_type": "gp3",
+				}},
+				"network_interface": []interface{}{map[string]interface{}{
+					"device_index": int32(0),
+				}},
+			},
+		}
+		blocks := &Handler{attributes: map[string]bool{"metadata_options": true, "root_block_device": true, "network_interface": true}}
+		differences, err := blocks.Compare(resources[0], NewResource(resources[0].ID, ec2Instance), &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differenceDetails(differences), ShouldResemble, map[string]string{
+			"metadata_options[0].http_tokens":  "AWS: required, Terraform: optional",
+			"root_block_device[0].volume_type": "AWS: gp3, Terraform: gp2",
+		})
+	})
+
+	Convey("ebs_block_device and network_interface sets are compared whatever their order in the state", t, func() {
+		stateInstance := &entities.Instance{}
+		stateInstance.Attributes.InstanceID = "i-1"
+		stateInstance.Attributes.EBSBlockDevice = []interface{}{
+			map[string]interface{}{"device_name": "/dev/sdg", "volume_size": float64(20)},
+			map[string]interface{}{"device_name": "/dev/sdf", "volume_size": float64(10)},
+		}
+		stateInstance.Attributes.NetworkInterface = []interface{}{
+			map[string]interface{}{"device_index": float64(10), "network_interface_id": "eni-3"},
+			map[string]interface{}{"device_index": float64(2), "network_interface_id": "eni-2"},
+		}
+		resources, err := handler.Normalize(registry.State{ResourceType: {{Address: "aws_instance.web", Instance: stateInstance}}})
+		So(err, ShouldBeNil)
+
+		ec2Instance := &entities.EC2Instance{Blocks: map[string]interface{}{
+			"ebs_block_device": []interface{}{
+				map[string]interface{}{"device_name": "/dev/sdf", "volume_size": int32(10)},
+				map[string]interface{}{"device_name": "/dev/sdg", "volume_size": int32(20)},
+			},
+			"network_interface": []interface{}{
+				map[string]interface{}{"device_index": int32(2), "network_interface_id": "eni-2"},
+				map[string]interface{}{"device_index": int32(10), "network_interface_id": "eni-3"},
+			},
+		}}
+		blocks := &Handler{attributes: map[string]bool{"ebs_block_device": true, "network_interface": true}}
+		differences, err := blocks.Compare(resources[0], NewResource("i-1", ec2Instance), &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differences, ShouldBeEmpty)
+	})
+}
+
+// differenceDetails maps the attribute paths of the differences to their printed form
+func differenceDetails(differences []*entities.Difference) map[string]string {
+	details := make(map[string]string)
+	for _, difference := range differences {
+		details[difference.Attribute] = difference.String()
+	}
+	return details
+}
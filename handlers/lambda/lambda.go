@@ -0,0 +1,212 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awslambda "github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const ResourceType = "aws_lambda_function"
+
+type (
+	// API is the part of the Lambda client read by the handler
+	API interface {
+		GetFunction(ctx context.Context, params *awslambda.GetFunctionInput, optFns ...func(*awslambda.Options)) (*awslambda.GetFunctionOutput, error)
+	}
+
+	// Handler checks the drift of aws_lambda_function resources, including the hash of their deployed code
+	Handler struct {
+		client API
+	}
+)
+
+func NewHandler(client API) *Handler {
+	return &Handler{client: client}
+}
+
+func (h *Handler) Types() []string {
+	return []string{ResourceType}
+}
+
+// Normalize decodes the functions of the state, identified by their function name
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[ResourceType]))
+	for _, stateResource := range state[ResourceType] {
+		attributes := make(map[string]interface{})
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &attributes); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+
+		values := make(map[string]interface{}, len(attributes))
+		for key, value := range attributes {
+			values[key] = value
+		}
+		values["tags"] = map[string]string{}
+		if tags := utils.MergedTags(utils.StringMap(attributes["tags"]), utils.StringMap(attributes["tags_all"])); tags != nil {
+			values["tags"] = tags
+		}
+		resources = append(resources, &registry.Resource{
+			Type:    ResourceType,
+			Address: stateResource.Address,
+			ID:      utils.StringValue(attributes["function_name"]),
+			Object:  attributes,
+			Values:  values,
+		})
+	}
+	return resources, nil
+}
+
+// Fetch gets the configuration of the functions from Lambda, the functions that do not exist any more are left out
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	awsResources := make(map[string]*registry.Resource, len(resources))
+	for _, name := range registry.IDs(resources) {
+		output, err := h.client.GetFunction(ctx, &awslambda.GetFunctionInput{FunctionName: aws.String(name)})
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			utils.Logger.Sugar().Errorf("failed to get lambda function %s: %v", name, err)
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+			}
+		}
+		values := functionValues(output.Configuration)
+		values["tags"] = output.Tags
+		if output.Tags == nil {
+			values["tags"] = map[string]string{}
+		}
+		awsResources[name] = &registry.Resource{Type: ResourceType, ID: name, Object: values, Values: values}
+	}
+	return awsResources, nil
+}
+
+// Compare compares a function from Lambda with the terraform state. Environment variables are compared by name, e.g.
+// environment.variables.DB_HOST, and their values are redacted in the differences
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	tfValues := tfResource.Object.(map[string]interface{})
+	awsValues := awsResource.Values
+	keys := utils.SortedKeys(awsValues)
+
+	differences := make([]*entities.Difference, 0)
+	for _, key := range keys {
+		switch key {
+		case "tags":
+			awsTags, _ := awsValues[key].(map[string]string)
+			tags, tagsAll := utils.StringMap(tfValues["tags"]), utils.StringMap(tfValues["tags_all"])
+			differences = append(differences, utils.TagDifferences(awsTags, tags, tagsAll, options.IncludeReservedTags)...)
+		case "environment":
+			awsVariables, _ := awsValues[key].(map[string]string)
+			tfVariables := map[string]string{}
+			for _, environment := range blocks(tfValues[key]) {
+				tfVariables = utils.StringMap(environment["variables"])
+			}
+			differences = append(differences, variableDifferences(awsVariables, tfVariables)...)
+		case "vpc_config":
+			awsConfig, _ := awsValues[key].(map[string][]string)
+			tfConfig := map[string]interface{}{}
+			for _, config := range blocks(tfValues[key]) {
+				tfConfig = config
+			}
+			for _, attribute := range []string{"security_group_ids", "subnet_ids"} {
+				differences = append(differences, utils.SetDifferences("vpc_config."+attribute, awsConfig[attribute], utils.StringList(tfConfig[attribute]))...)
+			}
+		case "source_code_hash":
+			// functions deployed from an image have no code hash in the state
+			tfHash := utils.StringValue(tfValues["source_code_hash"])
+			if tfHash == "" {
+				tfHash = utils.StringValue(tfValues["code_sha256"])
+			}
+			if tfHash != "" && tfHash != awsValues[key] {
+				differences = append(differences, entities.NewDifference(key, tfHash, awsValues[key]))
+			}
+		default:
+			differences = append(differences, utils.CompareNested(key, awsValues[key], tfValues[key])...)
+		}
+	}
+	return differences, nil
+}
+
+// functionValues turns the function configuration into its terraform shape, the environment and the VPC config are
+// kept as maps to be compared by name
+func functionValues(configuration *types.FunctionConfiguration) map[string]interface{} {
+	layers := make([]interface{}, 0, len(configuration.Layers))
+	for _, layer := range configuration.Layers {
+		layers = append(layers, aws.ToString(layer.Arn))
+	}
+	variables := make(map[string]string)
+	if configuration.Environment != nil {
+		for name, value := range configuration.Environment.Variables {
+			variables[name] = value
+		}
+	}
+	vpcConfig := map[string][]string{"security_group_ids": {}, "subnet_ids": {}}
+	if configuration.VpcConfig != nil {
+		vpcConfig["security_group_ids"] = append(vpcConfig["security_group_ids"], configuration.VpcConfig.SecurityGroupIds...)
+		vpcConfig["subnet_ids"] = append(vpcConfig["subnet_ids"], configuration.VpcConfig.SubnetIds...)
+	}
+	return map[string]interface{}{
+		"runtime":          string(configuration.Runtime),
+		"handler":          aws.ToString(configuration.Handler),
+		"memory_size":      aws.ToInt32(configuration.MemorySize),
+		"timeout":          aws.ToInt32(configuration.Timeout),
+		"role":             aws.ToString(configuration.Role),
+		"layers":           layers,
+		"environment":      variables,
+		"vpc_config":       vpcConfig,
+		"source_code_hash": aws.ToString(configuration.CodeSha256),
+	}
+}
+
+// blocks returns the objects of a nested block list decoded from the state
+func blocks(value interface{}) []map[string]interface{} {
+	list, _ := value.([]interface{})
+	objects := make([]map[string]interface{}, 0, len(list))
+	for _, element := range list {
+		if object, ok := element.(map[string]interface{}); ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+// variableDifferences compares the environment variables by name, sorted so that the report is stable, with their
+// values redacted as they often hold secrets
+func variableDifferences(awsVariables, tfVariables map[string]string) []*entities.Difference {
+	names := make([]string, 0, len(awsVariables)+len(tfVariables))
+	for name := range awsVariables {
+		names = append(names, name)
+	}
+	for name := range tfVariables {
+		names = utils.AppendUnique(names, name)
+	}
+	sort.Strings(names)
+
+	differences := make([]*entities.Difference, 0)
+	for _, name := range names {
+		awsValue, inAWS := awsVariables[name]
+		tfValue, inTerraform := tfVariables[name]
+		switch {
+		case !inTerraform:
+			differences = append(differences, entities.NewDifference("environment.variables."+name, nil, awsValue).Redact())
+		case !inAWS:
+			differences = append(differences, entities.NewDifference("environment.variables."+name, tfValue, nil).Redact())
+		case awsValue != tfValue:
+			differences = append(differences, entities.NewDifference("environment.variables."+name, tfValue, awsValue).Redact())
+		}
+	}
+	return differences
+}
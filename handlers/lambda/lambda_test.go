@@ -0,0 +1,90 @@
+package lambda
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awslambda "github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// lambdaClient returns the functions it holds by name, the other functions are not found
+type lambdaClient struct {
+	functions map[string]*awslambda.GetFunctionOutput
+}
+
+func (c *lambdaClient) GetFunction(ctx context.Context, params *awslambda.GetFunctionInput, optFns ...func(*awslambda.Options)) (*awslambda.GetFunctionOutput, error) {
+	function, ok := c.functions[aws.ToString(params.FunctionName)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Function not found")}
+	}
+	return function, nil
+}
+
+func TestLambdaHandler(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	state, err := registry.LoadState("../../testdata/lambda.tfstate.json")
+	client := &lambdaClient{functions: map[string]*awslambda.GetFunctionOutput{
+		"orders-api": {
+			Configuration: &types.FunctionConfiguration{
+				FunctionName: aws.String("orders-api"),
+				Runtime:      types.RuntimePython312,
+				Handler:      aws.String("app.handler"),
+				MemorySize:   aws.Int32(512),
+				Timeout:      aws.Int32(30),
+				Role:         aws.String("arn:aws:iam::123456789012:role/orders-api"),
+				Layers:       []types.Layer{{Arn: aws.String("arn:aws:lambda:us-west-2:123456789012:layer:common:4")}},
+				Environment: &types.EnvironmentResponse{Variables: map[string]string{
+					"DB_HOST":     "orders.cluster-abc.us-west-2.rds.amazonaws.com",
+					"DB_PASSWORD": "rotated",
+					"DEBUG":       "true",
+				}},
+				VpcConfig: &types.VpcConfigResponse{
+					SecurityGroupIds: []string{"sg-0a1b2c3d"},
+					SubnetIds:        []string{"subnet-4e5f6a7b", "subnet-0a1b2c3d"},
+				},
+				CodeSha256: aws.String("Zm9vYmFyYmF6cXV4cXV1eGNvcmdlZ3JhdWx0Z2FycGx5"),
+			},
+			Tags: map[string]string{"Name": "orders-api"},
+		},
+	}}
+	handler := NewHandler(client)
+
+	Convey("a function that does not exist any more is left out", t, func() {
+		So(err, ShouldBeNil)
+		resources, err := handler.Normalize(state)
+		So(err, ShouldBeNil)
+		So(resources, ShouldHaveLength, 2)
+		awsResources, err := handler.Fetch(context.Background(), resources)
+		So(err, ShouldBeNil)
+		So(awsResources, ShouldHaveLength, 1)
+		So(awsResources, ShouldContainKey, "orders-api")
+	})
+
+	Convey("an out of band code deploy, memory and environment changes are reported with redacted values", t, func() {
+		resources, _ := handler.Normalize(state)
+		awsResources, _ := handler.Fetch(context.Background(), resources)
+		differences, err := handler.Compare(resources[0], awsResources[resources[0].ID], &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differences, ShouldHaveLength, 4)
+		So(differences[0].Attribute, ShouldEqual, "environment.variables.DB_PASSWORD")
+		So(differences[0].Kind, ShouldEqual, entities.ChangeKindChanged)
+		So(differences[0].Expected, ShouldEqual, entities.RedactedValue)
+		So(differences[0].Actual, ShouldEqual, entities.RedactedValue)
+		So(differences[1].Attribute, ShouldEqual, "environment.variables.DEBUG")
+		So(differences[1].Kind, ShouldEqual, entities.ChangeKindAdded)
+		So(differences[1].Expected, ShouldEqual, entities.MissingValue)
+		So(differences[1].Actual, ShouldEqual, entities.RedactedValue)
+		So(differences[2].Attribute, ShouldEqual, "memory_size")
+		So(differences[2].Expected, ShouldEqual, "256")
+		So(differences[2].Actual, ShouldEqual, "512")
+		So(differences[3].Attribute, ShouldEqual, "source_code_hash")
+	})
+}
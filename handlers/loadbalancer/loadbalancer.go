@@ -0,0 +1,476 @@
+package loadbalancer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const (
+	ResourceTypeLoadBalancer          = "aws_lb"
+	ResourceTypeListener              = "aws_lb_listener"
+	ResourceTypeListenerRule          = "aws_lb_listener_rule"
+	ResourceTypeTargetGroup           = "aws_lb_target_group"
+	ResourceTypeTargetGroupAttachment = "aws_lb_target_group_attachment"
+)
+
+// setAttributes are the unordered lists of the resources, they are compared value by value
+var setAttributes = []string{"security_groups", "subnets"}
+
+// maxDescribeArns is the number of ARNs the Describe calls of ELBv2 take at once
+const maxDescribeArns = 20
+
+type (
+	// API is the part of the ELBv2 client read by the handlers
+	API interface {
+		DescribeLoadBalancers(ctx context.Context, params *elb.DescribeLoadBalancersInput, optFns ...func(*elb.Options)) (*elb.DescribeLoadBalancersOutput, error)
+		DescribeLoadBalancerAttributes(ctx context.Context, params *elb.DescribeLoadBalancerAttributesInput, optFns ...func(*elb.Options)) (*elb.DescribeLoadBalancerAttributesOutput, error)
+		DescribeListeners(ctx context.Context, params *elb.DescribeListenersInput, optFns ...func(*elb.Options)) (*elb.DescribeListenersOutput, error)
+		DescribeRules(ctx context.Context, params *elb.DescribeRulesInput, optFns ...func(*elb.Options)) (*elb.DescribeRulesOutput, error)
+		DescribeTargetGroups(ctx context.Context, params *elb.DescribeTargetGroupsInput, optFns ...func(*elb.Options)) (*elb.DescribeTargetGroupsOutput, error)
+		DescribeTargetHealth(ctx context.Context, params *elb.DescribeTargetHealthInput, optFns ...func(*elb.Options)) (*elb.DescribeTargetHealthOutput, error)
+	}
+
+	// Handler checks the drift of a load balancing resource type, its resources are compared attribute by attribute
+	// with the state and their nested blocks by path, e.g. health_check[0].path
+	Handler struct {
+		client       API
+		resourceType string
+		fetch        fetcher
+	}
+
+	// fetcher describes resources by ARN in their terraform shape keyed by ARN, in a single call of at most
+	// maxDescribeArns ARNs. Like that call, it fails with a NotFound error when one of the ARNs does not exist
+	fetcher func(ctx context.Context, client API, arns []string) (map[string]map[string]interface{}, error)
+
+	// stateResource is a load balancing resource of the state with its attributes in their terraform shape
+	stateResource struct {
+		attributes map[string]interface{}
+		// targets are the registrations of a target group managed by aws_lb_target_group_attachment resources, written
+		// id:port, nil when the registrations are not managed by terraform
+		targets []string
+	}
+)
+
+// NewHandlers creates the handlers of the load balancing resource types
+func NewHandlers(client API) []registry.Handler {
+	return []registry.Handler{
+		&Handler{client: client, resourceType: ResourceTypeLoadBalancer, fetch: describeLoadBalancers},
+		&Handler{client: client, resourceType: ResourceTypeListener, fetch: describeListeners},
+		&Handler{client: client, resourceType: ResourceTypeListenerRule, fetch: describeRules},
+		&Handler{client: client, resourceType: ResourceTypeTargetGroup, fetch: describeTargetGroups},
+	}
+}
+
+// Types returns the type of the handler, the target group handler also checks the target group attachments
+func (h *Handler) Types() []string {
+	if h.resourceType == ResourceTypeTargetGroup {
+		return []string{ResourceTypeTargetGroup, ResourceTypeTargetGroupAttachment}
+	}
+	return []string{h.resourceType}
+}
+
+// Normalize decodes the resources of the handler type, identified by their ARN. The target groups get the targets of
+// their aws_lb_target_group_attachment resources
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources, err := normalize(state, h.resourceType)
+	if err != nil || h.resourceType != ResourceTypeTargetGroup {
+		return resources, err
+	}
+
+	attachments, err := normalize(state, ResourceTypeTargetGroupAttachment)
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		for _, targetGroup := range resources {
+			if targetGroup.ID != utils.StringValue(attachment.Values["target_group_arn"]) {
+				continue
+			}
+			// a target registered without a port receives the traffic on the port of the target group
+			port := utils.StringValue(attachment.Values["port"])
+			if port == "" {
+				port = utils.StringValue(targetGroup.Values["port"])
+			}
+			tfTargetGroup := targetGroup.Object.(*stateResource)
+			tfTargetGroup.targets = append(tfTargetGroup.targets, utils.StringValue(attachment.Values["target_id"])+":"+port)
+		}
+	}
+	return resources, nil
+}
+
+// Fetch describes the resources by ARN, maxDescribeArns at a time. The resources that do not exist any more are left
+// out
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	awsResources := make(map[string]*registry.Resource, len(resources))
+	arns := registry.IDs(resources)
+	for start := 0; start < len(arns); start += maxDescribeArns {
+		batch := arns[start:min(start+maxDescribeArns, len(arns))]
+		described, err := h.fetch(ctx, h.client, batch)
+		if isNotFound(err) {
+			// a call fails as a whole when one of its ARNs does not exist, the ARNs are then described one at a time
+			described, err = h.fetchEach(ctx, batch)
+		}
+		if err != nil {
+			utils.Logger.Sugar().Errorf("failed to describe %s: %v", h.resourceType, err)
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+			}
+		}
+		for arn, values := range described {
+			awsResources[arn] = &registry.Resource{Type: h.resourceType, ID: arn, Object: values, Values: values}
+		}
+	}
+	return awsResources, nil
+}
+
+// fetchEach describes the resources one ARN at a time, leaving out the ARNs that do not exist
+func (h *Handler) fetchEach(ctx context.Context, arns []string) (map[string]map[string]interface{}, error) {
+	described := make(map[string]map[string]interface{}, len(arns))
+	for _, arn := range arns {
+		values, err := h.fetch(ctx, h.client, []string{arn})
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(described, values)
+	}
+	return described, nil
+}
+
+// Compare compares a load balancing resource from AWS with the terraform state. The conditions of a listener rule are
+// compared by field and value, e.g. condition.path_pattern./api/*, and the targets of a target group only when they
+// are managed by aws_lb_target_group_attachment resources
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	tfResourceState := tfResource.Object.(*stateResource)
+	tfValues := tfResourceState.attributes
+	awsValues := awsResource.Values
+	keys := utils.SortedKeys(awsValues)
+
+	differences := make([]*entities.Difference, 0)
+	for _, key := range keys {
+		switch {
+		case key == "condition":
+			awsConditions, _ := awsValues[key].(map[string][]string)
+			differences = append(differences, conditionDifferences(awsConditions, conditionValues(tfValues[key]))...)
+		case key == "targets":
+			if tfResourceState.targets == nil {
+				continue
+			}
+			awsTargets, _ := awsValues[key].([]string)
+			differences = append(differences, utils.SetDifferences(key, awsTargets, tfResourceState.targets)...)
+		case slices.Contains(setAttributes, key):
+			awsSet, _ := awsValues[key].([]string)
+			differences = append(differences, utils.SetDifferences(key, awsSet, utils.StringList(tfValues[key]))...)
+		default:
+			differences = append(differences, utils.CompareNested(key, awsValues[key], tfValues[key])...)
+		}
+	}
+	return differences, nil
+}
+
+func describeLoadBalancers(ctx context.Context, client API, arns []string) (map[string]map[string]interface{}, error) {
+	output, err := client.DescribeLoadBalancers(ctx, &elb.DescribeLoadBalancersInput{LoadBalancerArns: arns})
+	if err != nil {
+		return nil, err
+	}
+
+	described := make(map[string]map[string]interface{}, len(output.LoadBalancers))
+	for _, loadBalancer := range output.LoadBalancers {
+		subnets := make([]string, 0, len(loadBalancer.AvailabilityZones))
+		for _, zone := range loadBalancer.AvailabilityZones {
+			subnets = append(subnets, aws.ToString(zone.SubnetId))
+		}
+		values := map[string]interface{}{
+			"name":               aws.ToString(loadBalancer.LoadBalancerName),
+			"internal":           loadBalancer.Scheme == types.LoadBalancerSchemeEnumInternal,
+			"load_balancer_type": string(loadBalancer.Type),
+			"ip_address_type":    string(loadBalancer.IpAddressType),
+			"security_groups":    append([]string{}, loadBalancer.SecurityGroups...),
+			"subnets":            subnets,
+		}
+
+		// the attributes are only returned one load balancer at a time
+		attributes, err := client.DescribeLoadBalancerAttributes(ctx, &elb.DescribeLoadBalancerAttributesInput{LoadBalancerArn: loadBalancer.LoadBalancerArn})
+		if err != nil {
+			return nil, err
+		}
+		for _, attribute := range attributes.Attributes {
+			switch aws.ToString(attribute.Key) {
+			case "deletion_protection.enabled":
+				values["enable_deletion_protection"] = aws.ToString(attribute.Value)
+			case "idle_timeout.timeout_seconds":
+				values["idle_timeout"] = aws.ToString(attribute.Value)
+			}
+		}
+		described[aws.ToString(loadBalancer.LoadBalancerArn)] = values
+	}
+	return described, nil
+}
+
+func describeListeners(ctx context.Context, client API, arns []string) (map[string]map[string]interface{}, error) {
+	output, err := client.DescribeListeners(ctx, &elb.DescribeListenersInput{ListenerArns: arns})
+	if err != nil {
+		return nil, err
+	}
+
+	described := make(map[string]map[string]interface{}, len(output.Listeners))
+	for _, listener := range output.Listeners {
+		// the default certificate is the only one returned with the listener, the others are aws_lb_listener_certificate
+		certificateArn := ""
+		for _, certificate := range listener.Certificates {
+			if certificate.IsDefault == nil || aws.ToBool(certificate.IsDefault) {
+				certificateArn = aws.ToString(certificate.CertificateArn)
+			}
+		}
+		described[aws.ToString(listener.ListenerArn)] = map[string]interface{}{
+			"load_balancer_arn": aws.ToString(listener.LoadBalancerArn),
+			"port":              aws.ToInt32(listener.Port),
+			"protocol":          string(listener.Protocol),
+			"ssl_policy":        aws.ToString(listener.SslPolicy),
+			"certificate_arn":   certificateArn,
+			"default_action":    actionValues(listener.DefaultActions),
+		}
+	}
+	return described, nil
+}
+
+func describeRules(ctx context.Context, client API, arns []string) (map[string]map[string]interface{}, error) {
+	output, err := client.DescribeRules(ctx, &elb.DescribeRulesInput{RuleArns: arns})
+	if err != nil {
+		return nil, err
+	}
+
+	described := make(map[string]map[string]interface{}, len(output.Rules))
+	for _, rule := range output.Rules {
+		conditions := make(map[string][]string)
+		for _, condition := range rule.Conditions {
+			field, conditionValues := aws.ToString(condition.Field), condition.Values
+			switch {
+			case condition.HostHeaderConfig != nil:
+				conditionValues = condition.HostHeaderConfig.Values
+			case condition.PathPatternConfig != nil:
+				conditionValues = condition.PathPatternConfig.Values
+			case condition.HttpRequestMethodConfig != nil:
+				conditionValues = condition.HttpRequestMethodConfig.Values
+			case condition.SourceIpConfig != nil:
+				conditionValues = condition.SourceIpConfig.Values
+			case condition.HttpHeaderConfig != nil:
+				field += "." + aws.ToString(condition.HttpHeaderConfig.HttpHeaderName)
+				conditionValues = condition.HttpHeaderConfig.Values
+			case condition.QueryStringConfig != nil:
+				conditionValues = nil
+				for _, pair := range condition.QueryStringConfig.Values {
+					conditionValues = append(conditionValues, aws.ToString(pair.Key)+"="+aws.ToString(pair.Value))
+				}
+			}
+			// terraform names the fields with underscores, e.g. path_pattern for path-pattern
+			field = strings.ReplaceAll(field, "-", "_")
+			conditions[field] = append(conditions[field], conditionValues...)
+		}
+		arn := aws.ToString(rule.RuleArn)
+		described[arn] = map[string]interface{}{
+			"listener_arn": listenerArn(arn),
+			"priority":     aws.ToString(rule.Priority),
+			"action":       actionValues(rule.Actions),
+			"condition":    conditions,
+		}
+	}
+	return described, nil
+}
+
+func describeTargetGroups(ctx context.Context, client API, arns []string) (map[string]map[string]interface{}, error) {
+	output, err := client.DescribeTargetGroups(ctx, &elb.DescribeTargetGroupsInput{TargetGroupArns: arns})
+	if err != nil {
+		return nil, err
+	}
+
+	described := make(map[string]map[string]interface{}, len(output.TargetGroups))
+	for _, targetGroup := range output.TargetGroups {
+		matcher := ""
+		if targetGroup.Matcher != nil {
+			matcher = aws.ToString(targetGroup.Matcher.HttpCode)
+			if matcher == "" {
+				matcher = aws.ToString(targetGroup.Matcher.GrpcCode)
+			}
+		}
+		values := map[string]interface{}{
+			"name":        aws.ToString(targetGroup.TargetGroupName),
+			"port":        aws.ToInt32(targetGroup.Port),
+			"protocol":    string(targetGroup.Protocol),
+			"vpc_id":      aws.ToString(targetGroup.VpcId),
+			"target_type": string(targetGroup.TargetType),
+			"health_check": []interface{}{map[string]interface{}{
+				"enabled":             aws.ToBool(targetGroup.HealthCheckEnabled),
+				"path":                aws.ToString(targetGroup.HealthCheckPath),
+				"port":                aws.ToString(targetGroup.HealthCheckPort),
+				"protocol":            string(targetGroup.HealthCheckProtocol),
+				"matcher":             matcher,
+				"interval":            aws.ToInt32(targetGroup.HealthCheckIntervalSeconds),
+				"timeout":             aws.ToInt32(targetGroup.HealthCheckTimeoutSeconds),
+				"healthy_threshold":   aws.ToInt32(targetGroup.HealthyThresholdCount),
+				"unhealthy_threshold": aws.ToInt32(targetGroup.UnhealthyThresholdCount),
+			}},
+		}
+
+		// the targets are only returned one target group at a time
+		health, err := client.DescribeTargetHealth(ctx, &elb.DescribeTargetHealthInput{TargetGroupArn: targetGroup.TargetGroupArn})
+		if err != nil {
+			return nil, err
+		}
+		targets := make([]string, 0, len(health.TargetHealthDescriptions))
+		for _, description := range health.TargetHealthDescriptions {
+			if description.Target == nil {
+				continue
+			}
+			targets = append(targets, aws.ToString(description.Target.Id)+":"+strconv.Itoa(int(aws.ToInt32(description.Target.Port))))
+		}
+		values["targets"] = targets
+		described[aws.ToString(targetGroup.TargetGroupArn)] = values
+	}
+	return described, nil
+}
+
+// actionValues turns the actions of a listener or rule into their terraform blocks, a forward to a single target group
+// is written with its target_group_arn like terraform does
+func actionValues(actions []types.Action) []interface{} {
+	sort.Slice(actions, func(i, j int) bool {
+		return aws.ToInt32(actions[i].Order) < aws.ToInt32(actions[j].Order)
+	})
+	values := make([]interface{}, 0, len(actions))
+	for _, action := range actions {
+		actionValue := map[string]interface{}{
+			"type":             string(action.Type),
+			"target_group_arn": aws.ToString(action.TargetGroupArn),
+		}
+		if action.Order != nil {
+			actionValue["order"] = aws.ToInt32(action.Order)
+		}
+		if redirect := action.RedirectConfig; redirect != nil {
+			actionValue["redirect"] = []interface{}{map[string]interface{}{
+				"host":        aws.ToString(redirect.Host),
+				"path":        aws.ToString(redirect.Path),
+				"port":        aws.ToString(redirect.Port),
+				"protocol":    aws.ToString(redirect.Protocol),
+				"query":       aws.ToString(redirect.Query),
+				"status_code": string(redirect.StatusCode),
+			}}
+		}
+		if response := action.FixedResponseConfig; response != nil {
+			actionValue["fixed_response"] = []interface{}{map[string]interface{}{
+				"content_type": aws.ToString(response.ContentType),
+				"message_body": aws.ToString(response.MessageBody),
+				"status_code":  aws.ToString(response.StatusCode),
+			}}
+		}
+		values = append(values, actionValue)
+	}
+	return values
+}
+
+// conditionValues turns the condition blocks of a listener rule into the values of every field, the same way as
+// describeRule names them
+func conditionValues(value interface{}) map[string][]string {
+	conditions := make(map[string][]string)
+	blocks, _ := value.([]interface{})
+	for _, block := range blocks {
+		condition, _ := block.(map[string]interface{})
+		for field, configs := range condition {
+			configList, _ := configs.([]interface{})
+			for _, config := range configList {
+				config, _ := config.(map[string]interface{})
+				switch field {
+				case "http_header":
+					name := field + "." + utils.StringValue(config["http_header_name"])
+					conditions[name] = append(conditions[name], utils.StringList(config["values"])...)
+				case "query_string":
+					conditions[field] = append(conditions[field], utils.StringValue(config["key"])+"="+utils.StringValue(config["value"]))
+				default:
+					conditions[field] = append(conditions[field], utils.StringList(config["values"])...)
+				}
+			}
+		}
+	}
+	return conditions
+}
+
+// conditionDifferences compares the values of every condition field, in any order
+func conditionDifferences(awsConditions, tfConditions map[string][]string) []*entities.Difference {
+	fields := make([]string, 0, len(awsConditions)+len(tfConditions))
+	for field := range awsConditions {
+		fields = utils.AppendUnique(fields, field)
+	}
+	for field := range tfConditions {
+		fields = utils.AppendUnique(fields, field)
+	}
+	sort.Strings(fields)
+
+	differences := make([]*entities.Difference, 0)
+	for _, field := range fields {
+		differences = append(differences, utils.SetDifferences("condition."+field, awsConditions[field], tfConditions[field])...)
+	}
+	return differences
+}
+
+// listenerArn returns the ARN of the listener of a rule, e.g. listener/app/web/50dc6c495c0c9188/f2f7dc8efc522ab2 for
+// listener-rule/app/web/50dc6c495c0c9188/f2f7dc8efc522ab2/9683b2d02a6cabee
+func listenerArn(ruleArn string) string {
+	arn := strings.Replace(ruleArn, ":listener-rule/", ":listener/", 1)
+	if index := strings.LastIndex(arn, "/"); index >= 0 {
+		return arn[:index]
+	}
+	return arn
+}
+
+// normalize decodes the resources of a load balancing type of the terraform state, identified by their ARN
+func normalize(state registry.State, resourceType string) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[resourceType]))
+	for _, resource := range state[resourceType] {
+		attributes := make(map[string]interface{})
+		if err := json.Unmarshal(resource.Instance.RawAttributes, &attributes); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+
+		id := utils.StringValue(attributes["arn"])
+		if id == "" {
+			id = utils.StringValue(attributes["id"])
+		}
+		resources = append(resources, &registry.Resource{
+			Type:    resourceType,
+			Address: resource.Address,
+			ID:      id,
+			Object:  &stateResource{attributes: attributes},
+			Values:  attributes,
+		})
+	}
+	return resources, nil
+}
+
+// isNotFound reports whether err is the error ELBv2 answers with when a resource of the ARNs does not exist
+func isNotFound(err error) bool {
+	var loadBalancerNotFound *types.LoadBalancerNotFoundException
+	var listenerNotFound *types.ListenerNotFoundException
+	var ruleNotFound *types.RuleNotFoundException
+	var targetGroupNotFound *types.TargetGroupNotFoundException
+	return errors.As(err, &loadBalancerNotFound) || errors.As(err, &listenerNotFound) ||
+		errors.As(err, &ruleNotFound) || errors.As(err, &targetGroupNotFound)
+}
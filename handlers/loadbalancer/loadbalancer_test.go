@@ -0,0 +1,223 @@
+package loadbalancer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/registry/registrytest"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const targetGroupArn = "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/web/0123456789abcdef"
+
+const loadBalancerArn = "arn:aws:elasticloadbalancing:us-west-2:123456789012:loadbalancer/app/web/50dc6c495c0c9188"
+
+// elbClient answers like ELBv2 for the resources it has, a Describe call fails with the NotFound error of the resource
+// type when one of its ARNs does not exist. calls counts the Describe calls by ARN
+type elbClient struct {
+	loadBalancers []types.LoadBalancer
+	attributes    []types.LoadBalancerAttribute
+	listeners     []types.Listener
+	rules         []types.Rule
+	targetGroups  []types.TargetGroup
+	targets       []types.TargetHealthDescription
+	calls         int
+}
+
+func (c *elbClient) DescribeLoadBalancers(ctx context.Context, params *elb.DescribeLoadBalancersInput, optFns ...func(*elb.Options)) (*elb.DescribeLoadBalancersOutput, error) {
+	c.calls++
+	loadBalancers, err := byArn(c.loadBalancers, params.LoadBalancerArns, func(loadBalancer types.LoadBalancer) *string { return loadBalancer.LoadBalancerArn }, &types.LoadBalancerNotFoundException{})
+	if err != nil {
+		return nil, err
+	}
+	return &elb.DescribeLoadBalancersOutput{LoadBalancers: loadBalancers}, nil
+}
+
+func (c *elbClient) DescribeLoadBalancerAttributes(ctx context.Context, params *elb.DescribeLoadBalancerAttributesInput, optFns ...func(*elb.Options)) (*elb.DescribeLoadBalancerAttributesOutput, error) {
+	return &elb.DescribeLoadBalancerAttributesOutput{Attributes: c.attributes}, nil
+}
+
+func (c *elbClient) DescribeListeners(ctx context.Context, params *elb.DescribeListenersInput, optFns ...func(*elb.Options)) (*elb.DescribeListenersOutput, error) {
+	c.calls++
+	listeners, err := byArn(c.listeners, params.ListenerArns, func(listener types.Listener) *string { return listener.ListenerArn }, &types.ListenerNotFoundException{})
+	if err != nil {
+		return nil, err
+	}
+	return &elb.DescribeListenersOutput{Listeners: listeners}, nil
+}
+
+func (c *elbClient) DescribeRules(ctx context.Context, params *elb.DescribeRulesInput, optFns ...func(*elb.Options)) (*elb.DescribeRulesOutput, error) {
+	c.calls++
+	rules, err := byArn(c.rules, params.RuleArns, func(rule types.Rule) *string { return rule.RuleArn }, &types.RuleNotFoundException{})
+	if err != nil {
+		return nil, err
+	}
+	return &elb.DescribeRulesOutput{Rules: rules}, nil
+}
+
+func (c *elbClient) DescribeTargetGroups(ctx context.Context, params *elb.DescribeTargetGroupsInput, optFns ...func(*elb.Options)) (*elb.DescribeTargetGroupsOutput, error) {
+	c.calls++
+	targetGroups, err := byArn(c.targetGroups, params.TargetGroupArns, func(targetGroup types.TargetGroup) *string { return targetGroup.TargetGroupArn }, &types.TargetGroupNotFoundException{})
+	if err != nil {
+		return nil, err
+	}
+	return &elb.DescribeTargetGroupsOutput{TargetGroups: targetGroups}, nil
+}
+
+func (c *elbClient) DescribeTargetHealth(ctx context.Context, params *elb.DescribeTargetHealthInput, optFns ...func(*elb.Options)) (*elb.DescribeTargetHealthOutput, error) {
+	return &elb.DescribeTargetHealthOutput{TargetHealthDescriptions: c.targets}, nil
+}
+
+// byArn returns the resources of the ARNs, or the notFound error when one of them does not exist
+func byArn[T any](resources []T, arns []string, arn func(T) *string, notFound error) ([]T, error) {
+	found := make([]T, 0, len(arns))
+	for _, want := range arns {
+		i := slices.IndexFunc(resources, func(resource T) bool { return aws.ToString(arn(resource)) == want })
+		if i < 0 {
+			return nil, notFound
+		}
+		found = append(found, resources[i])
+	}
+	return found, nil
+}
+
+func TestLoadBalancerHandlers(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	state, err := registry.LoadState("../../testdata/loadbalancer.tfstate.json")
+	forward := []types.Action{{Type: types.ActionTypeEnumForward, Order: aws.Int32(1), TargetGroupArn: aws.String(targetGroupArn)}}
+	client := &elbClient{
+		loadBalancers: []types.LoadBalancer{{
+			LoadBalancerArn:  aws.String(loadBalancerArn),
+			LoadBalancerName: aws.String("web"),
+			Scheme:           types.LoadBalancerSchemeEnumInternetFacing,
+			Type:             types.LoadBalancerTypeEnumApplication,
+			IpAddressType:    types.IpAddressTypeIpv4,
+			SecurityGroups:   []string{"sg-0a1b2c3d"},
+			AvailabilityZones: []types.AvailabilityZone{
+				{SubnetId: aws.String("subnet-4e5f6a7b")},
+				{SubnetId: aws.String("subnet-0a1b2c3d")},
+			},
+		}},
+		attributes: []types.LoadBalancerAttribute{
+			{Key: aws.String("deletion_protection.enabled"), Value: aws.String("true")},
+			{Key: aws.String("idle_timeout.timeout_seconds"), Value: aws.String("60")},
+		},
+		listeners: []types.Listener{{
+			ListenerArn:     aws.String("arn:aws:elasticloadbalancing:us-west-2:123456789012:listener/app/web/50dc6c495c0c9188/f2f7dc8efc522ab2"),
+			LoadBalancerArn: aws.String(loadBalancerArn),
+			Port:            aws.Int32(443),
+			Protocol:        types.ProtocolEnumHttps,
+			SslPolicy:       aws.String("ELBSecurityPolicy-TLS13-1-2-2021-06"),
+			Certificates:    []types.Certificate{{CertificateArn: aws.String("arn:aws:acm:us-west-2:123456789012:certificate/66666666-7777-8888-9999-000000000000")}},
+			DefaultActions:  forward,
+		}},
+		rules: []types.Rule{{
+			RuleArn:  aws.String("arn:aws:elasticloadbalancing:us-west-2:123456789012:listener-rule/app/web/50dc6c495c0c9188/f2f7dc8efc522ab2/9683b2d02a6cabee"),
+			Priority: aws.String("20"),
+			Actions:  forward,
+			Conditions: []types.RuleCondition{
+				{Field: aws.String("host-header"), HostHeaderConfig: &types.HostHeaderConditionConfig{Values: []string{"www.example.com"}}},
+				{Field: aws.String("path-pattern"), PathPatternConfig: &types.PathPatternConditionConfig{Values: []string{"/api/*", "/admin/*"}}},
+			},
+		}},
+		targetGroups: []types.TargetGroup{{
+			TargetGroupArn:             aws.String(targetGroupArn),
+			TargetGroupName:            aws.String("web"),
+			Port:                       aws.Int32(80),
+			Protocol:                   types.ProtocolEnumHttp,
+			VpcId:                      aws.String("vpc-0a1b2c3d"),
+			TargetType:                 types.TargetTypeEnumInstance,
+			HealthCheckEnabled:         aws.Bool(true),
+			HealthCheckPath:            aws.String("/"),
+			HealthCheckPort:            aws.String("traffic-port"),
+			HealthCheckProtocol:        types.ProtocolEnumHttp,
+			Matcher:                    &types.Matcher{HttpCode: aws.String("200")},
+			HealthCheckIntervalSeconds: aws.Int32(30),
+			HealthCheckTimeoutSeconds:  aws.Int32(5),
+			HealthyThresholdCount:      aws.Int32(3),
+			UnhealthyThresholdCount:    aws.Int32(3),
+		}},
+		targets: []types.TargetHealthDescription{
+			{Target: &types.TargetDescription{Id: aws.String("i-0aaa"), Port: aws.Int32(80)}},
+			{Target: &types.TargetDescription{Id: aws.String("i-0bbb"), Port: aws.Int32(80)}},
+		},
+	}
+	handlers := registry.New(NewHandlers(client)...)
+
+	Convey("an unchanged load balancer is not reported", t, func() {
+		So(err, ShouldBeNil)
+		results, err := registrytest.Check(handlers, ResourceTypeLoadBalancer, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_lb.web"].Differences
+		So(differences, ShouldBeEmpty)
+	})
+
+	Convey("a replaced listener certificate is reported", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypeListener, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_lb_listener.https"].Differences
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "certificate_arn")
+	})
+
+	Convey("a listener rule priority and condition values are reported", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypeListenerRule, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_lb_listener_rule.api"].Differences
+		So(differences, ShouldHaveLength, 2)
+		So(differences[0].Attribute, ShouldEqual, "condition.path_pattern./admin/*")
+		So(differences[0].Kind, ShouldEqual, entities.ChangeKindAdded)
+		So(differences[1].Attribute, ShouldEqual, "priority")
+		So(differences[1].Expected, ShouldEqual, "10")
+		So(differences[1].Actual, ShouldEqual, "20")
+	})
+
+	Convey("a health check change and a target registered outside terraform are reported", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypeTargetGroup, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_lb_target_group.web"].Differences
+		So(differences, ShouldHaveLength, 2)
+		So(differences[0].Attribute, ShouldEqual, "health_check[0].path")
+		So(differences[1].Attribute, ShouldEqual, "targets.i-0bbb:80")
+		So(differences[1].Kind, ShouldEqual, entities.ChangeKindAdded)
+	})
+
+	Convey("the resources are described in batches of ARNs, a deleted resource is left out of its batch", t, func() {
+		batched := &elbClient{attributes: client.attributes}
+		resources := make([]*registry.Resource, 0, 25)
+		for i := 0; i < 25; i++ {
+			arn := fmt.Sprintf("%s-%d", loadBalancerArn, i)
+			batched.loadBalancers = append(batched.loadBalancers, types.LoadBalancer{LoadBalancerArn: aws.String(arn)})
+			resources = append(resources, &registry.Resource{Type: ResourceTypeLoadBalancer, ID: arn})
+		}
+		handler := &Handler{client: batched, resourceType: ResourceTypeLoadBalancer, fetch: describeLoadBalancers}
+		awsResources, err := handler.Fetch(context.Background(), resources)
+		So(err, ShouldBeNil)
+		So(awsResources, ShouldHaveLength, 25)
+		So(batched.calls, ShouldEqual, 2)
+
+		// the second batch of 5 fails as a whole and is described again one ARN at a time
+		batched.calls = 0
+		batched.loadBalancers = batched.loadBalancers[:24]
+		awsResources, err = handler.Fetch(context.Background(), resources)
+		So(err, ShouldBeNil)
+		So(awsResources, ShouldHaveLength, 24)
+		So(awsResources, ShouldNotContainKey, resources[24].ID)
+		So(batched.calls, ShouldEqual, 7)
+	})
+
+	Convey("the listener of a rule is derived from the rule ARN", t, func() {
+		So(listenerArn("arn:aws:elasticloadbalancing:us-west-2:123456789012:listener-rule/app/web/50dc6c495c0c9188/f2f7dc8efc522ab2/9683b2d02a6cabee"),
+			ShouldEqual, "arn:aws:elasticloadbalancing:us-west-2:123456789012:listener/app/web/50dc6c495c0c9188/f2f7dc8efc522ab2")
+	})
+}
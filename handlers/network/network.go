@@ -0,0 +1,283 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const (
+	ResourceTypeVpc             = "aws_vpc"
+	ResourceTypeSubnet          = "aws_subnet"
+	ResourceTypeRouteTable      = "aws_route_table"
+	ResourceTypeRoute           = "aws_route"
+	ResourceTypeInternetGateway = "aws_internet_gateway"
+	ResourceTypeNatGateway      = "aws_nat_gateway"
+)
+
+// routeTargets are the attributes of a route naming where its traffic goes, only one of them is set on a route
+var routeTargets = []string{
+	"carrier_gateway_id", "core_network_arn", "egress_only_gateway_id", "gateway_id", "local_gateway_id",
+	"nat_gateway_id", "network_interface_id", "transit_gateway_id", "vpc_endpoint_id", "vpc_peering_connection_id",
+}
+
+type (
+	// fetcher is the provider method getting the VPC networking resources by id from AWS, in their terraform shape
+	fetcher func(awsProvider providers.AWSProvider, ctx context.Context, ids []string) (map[string]map[string]interface{}, error)
+
+	// Handler checks the drift of a VPC networking resource type, its resources are compared attribute by attribute
+	// with the state
+	Handler struct {
+		awsProvider  providers.AWSProvider
+		resourceType string
+		fetch        fetcher
+	}
+
+	// RouteHandler checks the drift of aws_route resources against the route of their destination in AWS
+	RouteHandler struct {
+		awsProvider providers.AWSProvider
+	}
+
+	// stateResource is a VPC networking resource of the state with its attributes in their terraform shape
+	stateResource struct {
+		attributes map[string]interface{}
+		// managedRoutes are the destinations of a route table managed by aws_route resources
+		managedRoutes []string
+	}
+)
+
+// NewHandlers creates the handlers of the VPC networking resource types
+func NewHandlers(awsProvider providers.AWSProvider) []registry.Handler {
+	return []registry.Handler{
+		&Handler{awsProvider: awsProvider, resourceType: ResourceTypeVpc, fetch: providers.AWSProvider.GetVpcs},
+		&Handler{awsProvider: awsProvider, resourceType: ResourceTypeSubnet, fetch: providers.AWSProvider.GetSubnets},
+		&Handler{awsProvider: awsProvider, resourceType: ResourceTypeRouteTable, fetch: providers.AWSProvider.GetRouteTables},
+		&Handler{awsProvider: awsProvider, resourceType: ResourceTypeInternetGateway, fetch: providers.AWSProvider.GetInternetGateways},
+		&Handler{awsProvider: awsProvider, resourceType: ResourceTypeNatGateway, fetch: providers.AWSProvider.GetNatGateways},
+		&RouteHandler{awsProvider: awsProvider},
+	}
+}
+
+func (h *Handler) Types() []string {
+	return []string{h.resourceType}
+}
+
+// Normalize decodes the resources of the handler type. The route tables get the destinations of their aws_route
+// resources, so that these routes are not reported twice
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources, err := normalize(state, h.resourceType)
+	if err != nil || h.resourceType != ResourceTypeRouteTable {
+		return resources, err
+	}
+
+	routes, err := normalize(state, ResourceTypeRoute)
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		routeTableID := utils.StringValue(route.Values["route_table_id"])
+		for _, routeTable := range resources {
+			if routeTable.ID == routeTableID {
+				tfRouteTable := routeTable.Object.(*stateResource)
+				tfRouteTable.managedRoutes = append(tfRouteTable.managedRoutes, routeDestination(route.Values, "destination_"))
+			}
+		}
+	}
+	return resources, nil
+}
+
+// Fetch gets the resources from AWS in their terraform shape
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	valuesMap, err := h.fetch(h.awsProvider, ctx, registry.IDs(resources))
+	if err != nil {
+		return nil, err
+	}
+	awsResources := make(map[string]*registry.Resource, len(valuesMap))
+	for id, values := range valuesMap {
+		awsResources[id] = &registry.Resource{Type: h.resourceType, ID: id, Object: values, Values: values}
+	}
+	return awsResources, nil
+}
+
+// Compare compares a VPC networking resource from AWS with the terraform state. Only the attributes read from AWS
+// are compared, the routes of a route table are compared by destination, e.g. route.0.0.0.0/0
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	tfValues := tfResource.Object.(*stateResource)
+	awsValues := awsResource.Values
+	keys := utils.SortedKeys(awsValues)
+
+	differences := make([]*entities.Difference, 0)
+	for _, key := range keys {
+		switch key {
+		case "tags":
+			awsTags, _ := awsValues[key].(map[string]string)
+			tags, tagsAll := utils.StringMap(tfValues.attributes["tags"]), utils.StringMap(tfValues.attributes["tags_all"])
+			differences = append(differences, utils.TagDifferences(awsTags, tags, tagsAll, options.IncludeReservedTags)...)
+		case "route":
+			awsRoutes, _ := awsValues[key].([]interface{})
+			tfRoutes, _ := tfValues.attributes[key].([]interface{})
+			differences = append(differences, routeDifferences(awsRoutes, tfRoutes, tfValues.managedRoutes)...)
+		case "propagating_vgws":
+			awsGateways, _ := awsValues[key].([]string)
+			differences = append(differences, utils.SetDifferences(key, awsGateways, utils.StringList(tfValues.attributes[key]))...)
+		default:
+			differences = append(differences, utils.CompareNested(key, awsValues[key], tfValues.attributes[key])...)
+		}
+	}
+	return differences, nil
+}
+
+func (h *RouteHandler) Types() []string {
+	return []string{ResourceTypeRoute}
+}
+
+func (h *RouteHandler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	return normalize(state, ResourceTypeRoute)
+}
+
+// Fetch gets the route tables of the routes from AWS, every route gets the route of its destination, or no route
+// when the route table does not have it
+func (h *RouteHandler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	routeTableIds := make([]string, 0)
+	for _, resource := range resources {
+		routeTableIds = utils.AppendUnique(routeTableIds, utils.StringValue(resource.Values["route_table_id"]))
+	}
+	routeTableMap, err := h.awsProvider.GetRouteTables(ctx, routeTableIds)
+	if err != nil {
+		return nil, err
+	}
+
+	awsResources := make(map[string]*registry.Resource, len(resources))
+	for _, resource := range resources {
+		routeTableID := utils.StringValue(resource.Values["route_table_id"])
+		routeTable, ok := routeTableMap[routeTableID]
+		if !ok {
+			continue
+		}
+		awsRoute := findRoute(routeTable, routeDestination(resource.Values, "destination_"))
+		values := map[string]interface{}{"route_table_id": routeTableID}
+		for _, target := range routeTargets {
+			values[target] = awsRoute[target]
+		}
+		awsResources[resource.ID] = &registry.Resource{Type: ResourceTypeRoute, ID: resource.ID, Object: awsRoute, Values: values}
+	}
+	return awsResources, nil
+}
+
+// Compare compares an aws_route with the route of the same destination in its route table in AWS
+func (h *RouteHandler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	awsRoute, _ := awsResource.Object.(map[string]interface{})
+	if awsRoute == nil {
+		return []*entities.Difference{entities.NewDifference("route", routeTarget(tfResource.Values), nil)}, nil
+	}
+	differences := make([]*entities.Difference, 0)
+	for _, target := range routeTargets {
+		differences = append(differences, utils.CompareNested(target, awsRoute[target], tfResource.Values[target])...)
+	}
+	return differences, nil
+}
+
+// normalize decodes the resources of a VPC networking type of the terraform state, the custom rules see the tags
+// merged with the default_tags
+func normalize(state registry.State, resourceType string) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[resourceType]))
+	for _, resource := range state[resourceType] {
+		attributes := make(map[string]interface{})
+		if err := json.Unmarshal(resource.Instance.RawAttributes, &attributes); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+
+		values := make(map[string]interface{}, len(attributes))
+		for key, value := range attributes {
+			values[key] = value
+		}
+		values["tags"] = map[string]string{}
+		if tags := utils.MergedTags(utils.StringMap(attributes["tags"]), utils.StringMap(attributes["tags_all"])); tags != nil {
+			values["tags"] = tags
+		}
+		resources = append(resources, &registry.Resource{
+			Type:    resourceType,
+			Address: resource.Address,
+			ID:      utils.StringValue(attributes["id"]),
+			Object:  &stateResource{attributes: attributes},
+			Values:  values,
+		})
+	}
+	return resources, nil
+}
+
+// findRoute returns the route of a route table in AWS with the destination
+func findRoute(routeTable map[string]interface{}, destination string) map[string]interface{} {
+	routes, _ := routeTable["route"].([]interface{})
+	for _, route := range routes {
+		if route, ok := route.(map[string]interface{}); ok && routeDestination(route, "") == destination {
+			return route
+		}
+	}
+	return nil
+}
+
+// routeDifferences compares the routes of a route table by destination, a route only in AWS is added and a route
+// only in terraform is removed. Routes managed by aws_route resources are checked with these resources
+func routeDifferences(awsRoutes, tfRoutes []interface{}, managedRoutes []string) []*entities.Difference {
+	targets := func(routes []interface{}) map[string]string {
+		routeMap := make(map[string]string)
+		for _, route := range routes {
+			if route, ok := route.(map[string]interface{}); ok {
+				routeMap[routeDestination(route, "")] = routeTarget(route)
+			}
+		}
+		return routeMap
+	}
+	awsTargets, tfTargets := targets(awsRoutes), targets(tfRoutes)
+
+	differences := make([]*entities.Difference, 0)
+	for destination, awsTarget := range awsTargets {
+		tfTarget, ok := tfTargets[destination]
+		switch {
+		case slices.Contains(managedRoutes, destination):
+			continue
+		case !ok:
+			differences = append(differences, entities.NewDifference("route."+destination, nil, awsTarget))
+		case tfTarget != awsTarget:
+			differences = append(differences, entities.NewDifference("route."+destination, tfTarget, awsTarget))
+		}
+	}
+	for destination, tfTarget := range tfTargets {
+		if _, ok := awsTargets[destination]; !ok && !slices.Contains(managedRoutes, destination) {
+			differences = append(differences, entities.NewDifference("route."+destination, tfTarget, nil))
+		}
+	}
+	return differences
+}
+
+// routeDestination returns the destination of a route, the attributes of aws_route have the destination_ prefix
+func routeDestination(route map[string]interface{}, prefix string) string {
+	for _, attribute := range []string{prefix + "cidr_block", prefix + "ipv6_cidr_block", "destination_prefix_list_id"} {
+		if destination := utils.StringValue(route[attribute]); destination != "" {
+			return destination
+		}
+	}
+	return ""
+}
+
+// routeTarget returns the target of a route as attribute=value, e.g. gateway_id=igw-0a1b2c3d
+func routeTarget(route map[string]interface{}) string {
+	targets := make([]string, 0, 1)
+	for _, attribute := range routeTargets {
+		if target := utils.StringValue(route[attribute]); target != "" {
+			targets = append(targets, attribute+"="+target)
+		}
+	}
+	return strings.Join(targets, ",")
+}
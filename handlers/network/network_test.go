@@ -0,0 +1,121 @@
+package network
+
+import (
+	"context"
+	"testing"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// routeTableProvider returns the same route tables for every call
+type routeTableProvider struct {
+	providers.AWSProvider
+	routeTables map[string]map[string]interface{}
+}
+
+func (p *routeTableProvider) GetRouteTables(ctx context.Context, routeTableIDs []string) (map[string]map[string]interface{}, error) {
+	return p.routeTables, nil
+}
+
+func TestNetworkHandlers(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	state, err := registry.LoadState("../../testdata/network.tfstate.json")
+
+	Convey("data sources are not loaded from the state", t, func() {
+		So(err, ShouldBeNil)
+		So(state, ShouldNotContainKey, "aws_availability_zones")
+		So(state.Has(ResourceTypeVpc, "aws_security_group"), ShouldBeTrue)
+		So(state[ResourceTypeRoute][0].Address, ShouldEqual, "aws_route.peering")
+	})
+
+	Convey("a changed VPC attribute is reported as drift", t, func() {
+		handler := &Handler{resourceType: ResourceTypeVpc}
+		resources, err := handler.Normalize(state)
+		So(err, ShouldBeNil)
+		So(registry.IDs(resources), ShouldResemble, []string{"vpc-0a1b2c3d"})
+		So(resources[0].Address, ShouldEqual, "aws_vpc.main")
+
+		awsVpc := map[string]interface{}{
+			"cidr_block":           "10.0.0.0/16",
+			"instance_tenancy":     "default",
+			"dhcp_options_id":      "dopt-0d1c2b3a",
+			"enable_dns_support":   true,
+			"enable_dns_hostnames": false,
+			"tags":                 map[string]string{"Name": "main"},
+		}
+		differences, err := handler.Compare(resources[0], &registry.Resource{Values: awsVpc}, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differenceDetails(differences), ShouldResemble, map[string]string{
+			"enable_dns_hostnames": "AWS: false, Terraform: true",
+		})
+	})
+
+	Convey("routes of a route table are compared by destination", t, func() {
+		handler := &Handler{resourceType: ResourceTypeRouteTable}
+		resources, err := handler.Normalize(state)
+		So(err, ShouldBeNil)
+		So(resources[0].Object.(*stateResource).managedRoutes, ShouldResemble, []string{"10.1.0.0/16"})
+
+		awsRouteTable := map[string]interface{}{
+			"vpc_id": "vpc-0a1b2c3d",
+			"route": []interface{}{
+				map[string]interface{}{"cidr_block": "0.0.0.0/0", "nat_gateway_id": "nat-0a1b2c3d4e5f60789"},
+				map[string]interface{}{"cidr_block": "10.2.0.0/16", "transit_gateway_id": "tgw-0a1b2c3d"},
+			},
+			"propagating_vgws": []string{},
+			"tags":             map[string]string{"Name": "public"},
+		}
+		differences, err := handler.Compare(resources[0], &registry.Resource{Values: awsRouteTable}, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differenceDetails(differences), ShouldResemble, map[string]string{
+			"route.0.0.0.0/0":   "AWS: nat_gateway_id=nat-0a1b2c3d4e5f60789, Terraform: gateway_id=igw-0f1e2d3c4b5a69788",
+			"route.10.2.0.0/16": "AWS: transit_gateway_id=tgw-0a1b2c3d, Terraform: <missing>",
+		})
+	})
+
+	Convey("an aws_route is compared with the route of its destination", t, func() {
+		routeTable := map[string]interface{}{
+			"route": []interface{}{
+				map[string]interface{}{"cidr_block": "10.1.0.0/16", "vpc_peering_connection_id": "pcx-0123456789abcdef0"},
+			},
+		}
+		awsProvider := &routeTableProvider{routeTables: map[string]map[string]interface{}{"rtb-0a1b2c3d4e5f": routeTable}}
+		handler := &RouteHandler{awsProvider: awsProvider}
+		resources, err := handler.Normalize(state)
+		So(err, ShouldBeNil)
+
+		awsResources, err := handler.Fetch(context.Background(), resources)
+		So(err, ShouldBeNil)
+		differences, err := handler.Compare(resources[0], awsResources[resources[0].ID], &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differences, ShouldBeEmpty)
+
+		routeTable["route"] = []interface{}{}
+		awsResources, err = handler.Fetch(context.Background(), resources)
+		So(err, ShouldBeNil)
+		differences, err = handler.Compare(resources[0], awsResources[resources[0].ID], &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(differences[0].Attribute, ShouldEqual, "route")
+		So(differences[0].Kind, ShouldEqual, entities.ChangeKindRemoved)
+
+		awsProvider.routeTables = map[string]map[string]interface{}{}
+		awsResources, err = handler.Fetch(context.Background(), resources)
+		So(err, ShouldBeNil)
+		So(awsResources, ShouldNotContainKey, resources[0].ID)
+	})
+}
+
+// differenceDetails maps the attribute paths of the differences to their printed form
+func differenceDetails(differences []*entities.Difference) map[string]string {
+	details := make(map[string]string)
+	for _, difference := range differences {
+		details[difference.Attribute] = difference.String()
+	}
+	return details
+}
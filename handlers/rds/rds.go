@@ -0,0 +1,221 @@
+package rds
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsrds "github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const (
+	ResourceTypeInstance = "aws_db_instance"
+	ResourceTypeCluster  = "aws_rds_cluster"
+)
+
+// setAttributes are the unordered lists of the resources, they are compared value by value
+var setAttributes = []string{"vpc_security_group_ids"}
+
+type (
+	// API is the part of the RDS client read by the handlers
+	API interface {
+		DescribeDBInstances(ctx context.Context, params *awsrds.DescribeDBInstancesInput, optFns ...func(*awsrds.Options)) (*awsrds.DescribeDBInstancesOutput, error)
+		DescribeDBClusters(ctx context.Context, params *awsrds.DescribeDBClustersInput, optFns ...func(*awsrds.Options)) (*awsrds.DescribeDBClustersOutput, error)
+	}
+
+	// Handler checks the drift of an RDS resource type, its resources are compared attribute by attribute with the
+	// state
+	Handler struct {
+		client       API
+		resourceType string
+		// idAttribute is the state attribute holding the identifier the resource is described with
+		idAttribute string
+		fetch       fetcher
+	}
+
+	// fetcher describes the resources by identifier, in their terraform shape
+	fetcher func(ctx context.Context, client API, identifiers []string) (map[string]map[string]interface{}, error)
+)
+
+// NewHandlers creates the handlers of the RDS resource types
+func NewHandlers(client API) []registry.Handler {
+	return []registry.Handler{
+		&Handler{client: client, resourceType: ResourceTypeInstance, idAttribute: "identifier", fetch: describeInstances},
+		&Handler{client: client, resourceType: ResourceTypeCluster, idAttribute: "cluster_identifier", fetch: describeClusters},
+	}
+}
+
+func (h *Handler) Types() []string {
+	return []string{h.resourceType}
+}
+
+// Normalize decodes the resources of the handler type, they are identified by their identifier, the id of an
+// aws_db_instance being its resource id
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[h.resourceType]))
+	for _, stateResource := range state[h.resourceType] {
+		attributes := make(map[string]interface{})
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &attributes); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+
+		values := make(map[string]interface{}, len(attributes))
+		for key, value := range attributes {
+			values[key] = value
+		}
+		values["tags"] = map[string]string{}
+		if tags := utils.MergedTags(utils.StringMap(attributes["tags"]), utils.StringMap(attributes["tags_all"])); tags != nil {
+			values["tags"] = tags
+		}
+		resources = append(resources, &registry.Resource{
+			Type:    h.resourceType,
+			Address: stateResource.Address,
+			ID:      utils.StringValue(attributes[h.idAttribute]),
+			Object:  attributes,
+			Values:  values,
+		})
+	}
+	return resources, nil
+}
+
+// Fetch describes the resources in RDS, the resources that do not exist any more are left out
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	awsResources := make(map[string]*registry.Resource, len(resources))
+	if len(resources) == 0 {
+		return awsResources, nil
+	}
+
+	valuesMap, err := h.fetch(ctx, h.client, registry.IDs(resources))
+	if err != nil {
+		utils.Logger.Sugar().Errorf("failed to describe %s: %v", h.resourceType, err)
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+	}
+	for id, values := range valuesMap {
+		awsResources[id] = &registry.Resource{Type: h.resourceType, ID: id, Object: values, Values: values}
+	}
+	return awsResources, nil
+}
+
+// Compare compares an RDS resource with the terraform state, only the attributes read from RDS are compared
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	tfValues := tfResource.Object.(map[string]interface{})
+	awsValues := awsResource.Values
+	keys := utils.SortedKeys(awsValues)
+
+	differences := make([]*entities.Difference, 0)
+	for _, key := range keys {
+		switch {
+		case key == "tags":
+			awsTags, _ := awsValues[key].(map[string]string)
+			tags, tagsAll := utils.StringMap(tfValues["tags"]), utils.StringMap(tfValues["tags_all"])
+			differences = append(differences, utils.TagDifferences(awsTags, tags, tagsAll, options.IncludeReservedTags)...)
+		case slices.Contains(setAttributes, key):
+			awsSet, _ := awsValues[key].([]string)
+			differences = append(differences, utils.SetDifferences(key, awsSet, utils.StringList(tfValues[key]))...)
+		default:
+			differences = append(differences, utils.CompareNested(key, awsValues[key], tfValues[key])...)
+		}
+	}
+	return differences, nil
+}
+
+func describeInstances(ctx context.Context, client API, identifiers []string) (map[string]map[string]interface{}, error) {
+	instanceMap := make(map[string]map[string]interface{})
+	paginator := awsrds.NewDescribeDBInstancesPaginator(client, &awsrds.DescribeDBInstancesInput{
+		Filters: []types.Filter{{Name: aws.String("db-instance-id"), Values: identifiers}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range page.DBInstances {
+			parameterGroup := ""
+			for _, group := range instance.DBParameterGroups {
+				parameterGroup = aws.ToString(group.DBParameterGroupName)
+			}
+			securityGroups := make([]string, 0, len(instance.VpcSecurityGroups))
+			for _, group := range instance.VpcSecurityGroups {
+				securityGroups = append(securityGroups, aws.ToString(group.VpcSecurityGroupId))
+			}
+			instanceMap[aws.ToString(instance.DBInstanceIdentifier)] = map[string]interface{}{
+				"instance_class":          aws.ToString(instance.DBInstanceClass),
+				"allocated_storage":       aws.ToInt32(instance.AllocatedStorage),
+				"max_allocated_storage":   aws.ToInt32(instance.MaxAllocatedStorage),
+				"storage_type":            aws.ToString(instance.StorageType),
+				"iops":                    aws.ToInt32(instance.Iops),
+				"storage_encrypted":       aws.ToBool(instance.StorageEncrypted),
+				"backup_retention_period": aws.ToInt32(instance.BackupRetentionPeriod),
+				"backup_window":           aws.ToString(instance.PreferredBackupWindow),
+				"maintenance_window":      aws.ToString(instance.PreferredMaintenanceWindow),
+				"multi_az":                aws.ToBool(instance.MultiAZ),
+				"publicly_accessible":     aws.ToBool(instance.PubliclyAccessible),
+				"parameter_group_name":    parameterGroup,
+				"deletion_protection":     aws.ToBool(instance.DeletionProtection),
+				"vpc_security_group_ids":  securityGroups,
+				"tags":                    tagMap(instance.TagList),
+			}
+		}
+	}
+	return instanceMap, nil
+}
+
+func describeClusters(ctx context.Context, client API, identifiers []string) (map[string]map[string]interface{}, error) {
+	clusterMap := make(map[string]map[string]interface{})
+	paginator := awsrds.NewDescribeDBClustersPaginator(client, &awsrds.DescribeDBClustersInput{
+		Filters: []types.Filter{{Name: aws.String("db-cluster-id"), Values: identifiers}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range page.DBClusters {
+			securityGroups := make([]string, 0, len(cluster.VpcSecurityGroups))
+			for _, group := range cluster.VpcSecurityGroups {
+				securityGroups = append(securityGroups, aws.ToString(group.VpcSecurityGroupId))
+			}
+			values := map[string]interface{}{
+				"backup_retention_period":         aws.ToInt32(cluster.BackupRetentionPeriod),
+				"preferred_backup_window":         aws.ToString(cluster.PreferredBackupWindow),
+				"preferred_maintenance_window":    aws.ToString(cluster.PreferredMaintenanceWindow),
+				"db_cluster_parameter_group_name": aws.ToString(cluster.DBClusterParameterGroup),
+				"deletion_protection":             aws.ToBool(cluster.DeletionProtection),
+				"storage_encrypted":               aws.ToBool(cluster.StorageEncrypted),
+				"vpc_security_group_ids":          securityGroups,
+				"tags":                            tagMap(cluster.TagList),
+			}
+			// the instance class and storage are only set on Multi-AZ DB clusters, Aurora clusters leave them to
+			// their instances
+			if cluster.DBClusterInstanceClass != nil {
+				values["db_cluster_instance_class"] = aws.ToString(cluster.DBClusterInstanceClass)
+				values["allocated_storage"] = aws.ToInt32(cluster.AllocatedStorage)
+				values["storage_type"] = aws.ToString(cluster.StorageType)
+				values["iops"] = aws.ToInt32(cluster.Iops)
+			}
+			clusterMap[aws.ToString(cluster.DBClusterIdentifier)] = values
+		}
+	}
+	return clusterMap, nil
+}
+
+// tagMap turns the RDS tag list into the terraform tags map
+func tagMap(tags []types.Tag) map[string]string {
+	tagsMap := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		tagsMap[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tagsMap
+}
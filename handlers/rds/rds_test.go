@@ -0,0 +1,90 @@
+package rds
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsrds "github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/registry/registrytest"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// rdsClient returns the same instances and clusters for every call
+type rdsClient struct {
+	instances []types.DBInstance
+	clusters  []types.DBCluster
+}
+
+func (c *rdsClient) DescribeDBInstances(ctx context.Context, params *awsrds.DescribeDBInstancesInput, optFns ...func(*awsrds.Options)) (*awsrds.DescribeDBInstancesOutput, error) {
+	return &awsrds.DescribeDBInstancesOutput{DBInstances: c.instances}, nil
+}
+
+func (c *rdsClient) DescribeDBClusters(ctx context.Context, params *awsrds.DescribeDBClustersInput, optFns ...func(*awsrds.Options)) (*awsrds.DescribeDBClustersOutput, error) {
+	return &awsrds.DescribeDBClustersOutput{DBClusters: c.clusters}, nil
+}
+
+func TestRDSHandlers(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	state, err := registry.LoadState("../../testdata/rds.tfstate.json")
+	client := &rdsClient{
+		instances: []types.DBInstance{{
+			DBInstanceIdentifier:       aws.String("orders"),
+			DBInstanceClass:            aws.String("db.r6g.large"),
+			AllocatedStorage:           aws.Int32(100),
+			StorageType:                aws.String("gp3"),
+			Iops:                       aws.Int32(3000),
+			StorageEncrypted:           aws.Bool(true),
+			BackupRetentionPeriod:      aws.Int32(7),
+			PreferredBackupWindow:      aws.String("03:00-04:00"),
+			PreferredMaintenanceWindow: aws.String("sun:05:00-sun:06:00"),
+			MultiAZ:                    aws.Bool(false),
+			PubliclyAccessible:         aws.Bool(false),
+			DBParameterGroups:          []types.DBParameterGroupStatus{{DBParameterGroupName: aws.String("orders-postgres16")}},
+			DeletionProtection:         aws.Bool(true),
+			VpcSecurityGroups:          []types.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-0a1b2c3d")}},
+			TagList:                    []types.Tag{{Key: aws.String("Name"), Value: aws.String("orders")}},
+		}},
+		clusters: []types.DBCluster{{
+			DBClusterIdentifier:        aws.String("analytics"),
+			BackupRetentionPeriod:      aws.Int32(1),
+			PreferredBackupWindow:      aws.String("02:00-03:00"),
+			PreferredMaintenanceWindow: aws.String("sat:04:00-sat:05:00"),
+			DBClusterParameterGroup:    aws.String("default.aurora-postgresql16"),
+			DeletionProtection:         aws.Bool(false),
+			StorageEncrypted:           aws.Bool(true),
+			VpcSecurityGroups:          []types.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-4e5f6a7b")}},
+		}},
+	}
+	handlers := registry.New(NewHandlers(client)...)
+
+	Convey("a resized instance with Multi-AZ turned off is reported", t, func() {
+		So(err, ShouldBeNil)
+		results, err := registrytest.Check(handlers, ResourceTypeInstance, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_db_instance.orders"].Differences
+		So(differences, ShouldHaveLength, 2)
+		So(differences[0].Attribute, ShouldEqual, "instance_class")
+		So(differences[0].Expected, ShouldEqual, "db.t4g.medium")
+		So(differences[0].Actual, ShouldEqual, "db.r6g.large")
+		So(differences[1].Attribute, ShouldEqual, "multi_az")
+		So(differences[1].Actual, ShouldEqual, "false")
+	})
+
+	Convey("the backup retention and deletion protection of a cluster are reported", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypeCluster, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_rds_cluster.analytics"].Differences
+		So(differences, ShouldHaveLength, 2)
+		So(differences[0].Attribute, ShouldEqual, "backup_retention_period")
+		So(differences[0].Actual, ShouldEqual, "1")
+		So(differences[1].Attribute, ShouldEqual, "deletion_protection")
+		So(differences[1].Actual, ShouldEqual, "false")
+	})
+}
@@ -0,0 +1,258 @@
+package route53
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsroute53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const ResourceType = "aws_route53_record"
+
+type (
+	// API is the part of the Route53 client read by the handler
+	API interface {
+		ListResourceRecordSets(ctx context.Context, params *awsroute53.ListResourceRecordSetsInput, optFns ...func(*awsroute53.Options)) (*awsroute53.ListResourceRecordSetsOutput, error)
+	}
+
+	// Handler checks the drift of aws_route53_record resources. The record sets of every hosted zone of the state are
+	// listed, so that the records created by hand in these zones are reported as unmanaged
+	Handler struct {
+		client API
+	}
+
+	// recordSet is the AWS side of a record
+	recordSet struct {
+		// zoneDefault is set on the NS and SOA records of the zone apex, created by Route53 with the hosted zone
+		zoneDefault bool
+	}
+)
+
+func NewHandler(client API) *Handler {
+	return &Handler{client: client}
+}
+
+func (h *Handler) Types() []string {
+	return []string{ResourceType}
+}
+
+// Normalize decodes the records of the state. They are identified like terraform does, by zone, name, type and set
+// identifier, e.g. Z0123456789_www.example.com_A, with the name normalized as it is listed by Route53
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[ResourceType]))
+	for _, stateResource := range state[ResourceType] {
+		attributes := make(map[string]interface{})
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &attributes); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+
+		name := utils.StringValue(attributes["fqdn"])
+		if name == "" {
+			name = utils.StringValue(attributes["name"])
+		}
+		resources = append(resources, &registry.Resource{
+			Type:    ResourceType,
+			Address: stateResource.Address,
+			ID: recordID(utils.StringValue(attributes["zone_id"]), name, utils.StringValue(attributes["type"]),
+				utils.StringValue(attributes["set_identifier"])),
+			Object: attributes,
+			Values: attributes,
+		})
+	}
+	return resources, nil
+}
+
+// Fetch lists the record sets of the hosted zones of the records, page by page. Every record set of the zones is
+// returned, the records of a zone that does not exist any more are left out
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	zoneIDs := make([]string, 0)
+	for _, resource := range resources {
+		zoneIDs = utils.AppendUnique(zoneIDs, utils.StringValue(resource.Object.(map[string]interface{})["zone_id"]))
+	}
+
+	awsResources := make(map[string]*registry.Resource)
+	for _, zoneID := range zoneIDs {
+		recordSets, err := listRecordSets(ctx, h.client, zoneID)
+		var noSuchZone *types.NoSuchHostedZone
+		if errors.As(err, &noSuchZone) {
+			continue
+		}
+		if err != nil {
+			utils.Logger.Sugar().Errorf("failed to list the records of hosted zone %s: %v", zoneID, err)
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+			}
+		}
+
+		// the apex is the name of the SOA record, there is one per zone
+		apex := ""
+		for _, set := range recordSets {
+			if set.Type == types.RRTypeSoa {
+				apex = recordName(aws.ToString(set.Name))
+			}
+		}
+		for _, set := range recordSets {
+			name := recordName(aws.ToString(set.Name))
+			id := recordID(zoneID, name, string(set.Type), aws.ToString(set.SetIdentifier))
+			awsResources[id] = &registry.Resource{
+				Type:   ResourceType,
+				ID:     id,
+				Object: &recordSet{zoneDefault: name == apex && (set.Type == types.RRTypeNs || set.Type == types.RRTypeSoa)},
+				Values: recordValues(set),
+			}
+		}
+	}
+	return awsResources, nil
+}
+
+// Compare compares a record set from Route53 with the terraform state, the values of a record are compared as a set
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	tfValues := tfResource.Object.(map[string]interface{})
+	awsValues := awsResource.Values
+	keys := utils.SortedKeys(awsValues)
+
+	differences := make([]*entities.Difference, 0)
+	for _, key := range keys {
+		switch key {
+		case "records":
+			awsRecords, _ := awsValues[key].([]string)
+			differences = append(differences, utils.SetDifferences(key, awsRecords, utils.StringList(tfValues[key]))...)
+		case "multivalue_answer_routing_policy":
+			// an unset policy is null or false in the state
+			if tfValue := tfValues[key] == true; tfValue != awsValues[key] {
+				differences = append(differences, entities.NewDifference(key, tfValue, awsValues[key]))
+			}
+		default:
+			differences = append(differences, utils.CompareNested(key, awsValues[key], tfValues[key])...)
+		}
+	}
+	return differences, nil
+}
+
+// Unmanaged returns the record sets of the hosted zones that are not in the state, apart from the NS and SOA records
+// of the zone apex
+func (h *Handler) Unmanaged(tfResources []*registry.Resource, awsResources map[string]*registry.Resource) []*registry.Resource {
+	managed := make(map[string]bool, len(tfResources))
+	for _, resource := range tfResources {
+		managed[resource.ID] = true
+	}
+
+	unmanaged := make([]*registry.Resource, 0)
+	for id, resource := range awsResources {
+		if managed[id] || resource.Object.(*recordSet).zoneDefault {
+			continue
+		}
+		unmanaged = append(unmanaged, resource)
+	}
+	sort.Slice(unmanaged, func(i, j int) bool {
+		return unmanaged[i].ID < unmanaged[j].ID
+	})
+	return unmanaged
+}
+
+func listRecordSets(ctx context.Context, client API, zoneID string) ([]types.ResourceRecordSet, error) {
+	recordSets := make([]types.ResourceRecordSet, 0)
+	paginator := awsroute53.NewListResourceRecordSetsPaginator(client, &awsroute53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		recordSets = append(recordSets, page.ResourceRecordSets...)
+	}
+	return recordSets, nil
+}
+
+// recordValues turns a record set into its terraform shape, the routing policies are blocks set only for the policy
+// of the record
+func recordValues(set types.ResourceRecordSet) map[string]interface{} {
+	records := make([]string, 0, len(set.ResourceRecords))
+	for _, record := range set.ResourceRecords {
+		value := aws.ToString(record.Value)
+		if set.Type == types.RRTypeTxt || set.Type == types.RRTypeSpf {
+			value = txtValue(value)
+		}
+		records = append(records, value)
+	}
+
+	values := map[string]interface{}{
+		"type":                             string(set.Type),
+		"records":                          records,
+		"health_check_id":                  aws.ToString(set.HealthCheckId),
+		"multivalue_answer_routing_policy": aws.ToBool(set.MultiValueAnswer),
+		"alias":                            []interface{}{},
+		"weighted_routing_policy":          []interface{}{},
+		"latency_routing_policy":           []interface{}{},
+		"failover_routing_policy":          []interface{}{},
+		"geolocation_routing_policy":       []interface{}{},
+	}
+	// alias records have no TTL, so it is not compared
+	if set.TTL != nil {
+		values["ttl"] = aws.ToInt64(set.TTL)
+	}
+	if set.AliasTarget != nil {
+		values["alias"] = []interface{}{map[string]interface{}{
+			"name":                   recordName(aws.ToString(set.AliasTarget.DNSName)),
+			"zone_id":                aws.ToString(set.AliasTarget.HostedZoneId),
+			"evaluate_target_health": set.AliasTarget.EvaluateTargetHealth,
+		}}
+	}
+	if set.Weight != nil {
+		values["weighted_routing_policy"] = []interface{}{map[string]interface{}{"weight": aws.ToInt64(set.Weight)}}
+	}
+	if set.Region != "" {
+		values["latency_routing_policy"] = []interface{}{map[string]interface{}{"region": string(set.Region)}}
+	}
+	if set.Failover != "" {
+		values["failover_routing_policy"] = []interface{}{map[string]interface{}{"type": string(set.Failover)}}
+	}
+	if set.GeoLocation != nil {
+		values["geolocation_routing_policy"] = []interface{}{map[string]interface{}{
+			"continent":   aws.ToString(set.GeoLocation.ContinentCode),
+			"country":     aws.ToString(set.GeoLocation.CountryCode),
+			"subdivision": aws.ToString(set.GeoLocation.SubdivisionCode),
+		}}
+	}
+	return values
+}
+
+// recordID formats the id of a record like the terraform provider, zone_name_type with the set identifier appended
+// for the records of a routing policy
+func recordID(zoneID, name, recordType, setIdentifier string) string {
+	id := strings.Join([]string{zoneID, recordName(name), strings.ToUpper(recordType)}, "_")
+	if setIdentifier != "" {
+		id += "_" + setIdentifier
+	}
+	return id
+}
+
+// recordName normalizes a record name as the state has it, Route53 lists fully qualified names ending with a dot
+// and escapes the * of wildcard records
+func recordName(name string) string {
+	name = strings.ReplaceAll(name, `\052`, "*")
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// txtValue removes the quotes Route53 lists TXT values with, values longer than 255 characters are split into
+// quoted strings that the state has joined
+func txtValue(value string) string {
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return value
+	}
+	return strings.ReplaceAll(value[1:len(value)-1], `""`, "")
+}
@@ -0,0 +1,171 @@
+package route53
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsroute53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const zoneID = "Z0123456789ABCDEFGHIJ"
+
+// route53Client lists the record sets of a zone in pages, the next page starts at the name of its first record
+type route53Client struct {
+	pages [][]types.ResourceRecordSet
+}
+
+func (c *route53Client) ListResourceRecordSets(ctx context.Context, params *awsroute53.ListResourceRecordSetsInput, optFns ...func(*awsroute53.Options)) (*awsroute53.ListResourceRecordSetsOutput, error) {
+	if aws.ToString(params.HostedZoneId) != zoneID {
+		return nil, &types.NoSuchHostedZone{Message: aws.String("No hosted zone found")}
+	}
+	page := 0
+	for i, records := range c.pages {
+		if aws.ToString(params.StartRecordName) == aws.ToString(records[0].Name) {
+			page = i
+		}
+	}
+	output := &awsroute53.ListResourceRecordSetsOutput{ResourceRecordSets: c.pages[page]}
+	if page+1 < len(c.pages) {
+		next := c.pages[page+1][0]
+		output.IsTruncated = true
+		output.NextRecordName = next.Name
+		output.NextRecordType = next.Type
+	}
+	return output, nil
+}
+
+func TestRoute53Handler(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	state, err := registry.LoadState("../../testdata/route53.tfstate.json")
+	client := &route53Client{pages: [][]types.ResourceRecordSet{
+		{
+			{
+				Name: aws.String("example.com."),
+				Type: types.RRTypeA,
+				AliasTarget: &types.AliasTarget{
+					DNSName:              aws.String("dualstack.web-1234567890.us-west-2.elb.amazonaws.com."),
+					HostedZoneId:         aws.String("Z1H1FL5HABSF5"),
+					EvaluateTargetHealth: true,
+				},
+			},
+			{
+				Name:            aws.String("example.com."),
+				Type:            types.RRTypeNs,
+				TTL:             aws.Int64(172800),
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("ns-1.awsdns-01.org.")}},
+			},
+			{
+				Name:            aws.String("example.com."),
+				Type:            types.RRTypeSoa,
+				TTL:             aws.Int64(900),
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("ns-1.awsdns-01.org. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400")}},
+			},
+			{
+				Name:            aws.String("example.com."),
+				Type:            types.RRTypeTxt,
+				TTL:             aws.Int64(3600),
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String(`"v=spf1 include:_spf.example.com ~all"`)}},
+			},
+		},
+		{
+			{
+				Name:            aws.String("api.example.com."),
+				Type:            types.RRTypeCname,
+				SetIdentifier:   aws.String("blue"),
+				Weight:          aws.Int64(50),
+				TTL:             aws.Int64(60),
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("blue.api.example.com")}},
+			},
+			{
+				Name:            aws.String("legacy.example.com."),
+				Type:            types.RRTypeCname,
+				TTL:             aws.Int64(300),
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("old-host.example.net")}},
+			},
+			{
+				Name:            aws.String("www.example.com."),
+				Type:            types.RRTypeA,
+				TTL:             aws.Int64(60),
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String("203.0.113.10")}, {Value: aws.String("203.0.113.11")}},
+			},
+		},
+	}}
+	handler := NewHandler(client)
+
+	compare := func(id string) ([]*entities.Difference, error) {
+		resources, err := handler.Normalize(state)
+		if err != nil {
+			return nil, err
+		}
+		awsResources, err := handler.Fetch(context.Background(), resources)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			if resource.ID == id {
+				return handler.Compare(resource, awsResources[id], &entities.ReportOptions{})
+			}
+		}
+		return nil, nil
+	}
+
+	Convey("the records of every page of the zone are fetched", t, func() {
+		So(err, ShouldBeNil)
+		resources, err := handler.Normalize(state)
+		So(err, ShouldBeNil)
+		awsResources, err := handler.Fetch(context.Background(), resources)
+		So(err, ShouldBeNil)
+		So(awsResources, ShouldHaveLength, 7)
+		So(awsResources, ShouldContainKey, zoneID+"_www.example.com_A")
+		So(awsResources, ShouldContainKey, zoneID+"_api.example.com_CNAME_blue")
+	})
+
+	Convey("a TTL lowered and a value added by hand are reported", t, func() {
+		differences, err := compare(zoneID + "_www.example.com_A")
+		So(err, ShouldBeNil)
+		So(differences, ShouldHaveLength, 2)
+		So(differences[0].Attribute, ShouldEqual, "records.203.0.113.11")
+		So(differences[0].Kind, ShouldEqual, entities.ChangeKindAdded)
+		So(differences[1].Attribute, ShouldEqual, "ttl")
+		So(differences[1].Expected, ShouldEqual, "300")
+		So(differences[1].Actual, ShouldEqual, "60")
+	})
+
+	Convey("unchanged alias and TXT records are not reported", t, func() {
+		differences, err := compare(zoneID + "_example.com_A")
+		So(err, ShouldBeNil)
+		So(differences, ShouldBeEmpty)
+		differences, err = compare(zoneID + "_example.com_TXT")
+		So(err, ShouldBeNil)
+		So(differences, ShouldBeEmpty)
+	})
+
+	Convey("a changed weight of a routing policy is reported", t, func() {
+		differences, err := compare(zoneID + "_api.example.com_CNAME_blue")
+		So(err, ShouldBeNil)
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "weighted_routing_policy[0].weight")
+		So(differences[0].Actual, ShouldEqual, "50")
+	})
+
+	Convey("records absent from the state are unmanaged, apart from the apex NS and SOA", t, func() {
+		resources, _ := handler.Normalize(state)
+		awsResources, _ := handler.Fetch(context.Background(), resources)
+		unmanaged := handler.Unmanaged(resources, awsResources)
+		So(unmanaged, ShouldHaveLength, 1)
+		So(unmanaged[0].ID, ShouldEqual, zoneID+"_legacy.example.com_CNAME")
+	})
+
+	Convey("record names are normalized as the state has them", t, func() {
+		So(recordName(`\052.Example.com.`), ShouldEqual, "*.example.com")
+		So(txtValue(`"first part""second part"`), ShouldEqual, "first partsecond part")
+	})
+}
@@ -0,0 +1,385 @@
+package s3bucket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const (
+	ResourceType                  = "aws_s3_bucket"
+	ResourceTypePolicy            = "aws_s3_bucket_policy"
+	ResourceTypeVersioning        = "aws_s3_bucket_versioning"
+	ResourceTypeEncryption        = "aws_s3_bucket_server_side_encryption_configuration"
+	ResourceTypePublicAccessBlock = "aws_s3_bucket_public_access_block"
+	ResourceTypeLifecycle         = "aws_s3_bucket_lifecycle_configuration"
+)
+
+type (
+	// API is the part of the S3 client read by the handler
+	API interface {
+		HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+		GetBucketTagging(ctx context.Context, params *s3.GetBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error)
+		GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error)
+		GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error)
+		GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error)
+		GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error)
+		GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error)
+	}
+
+	// Handler checks the drift of an S3 bucket resource type, aws_s3_bucket or one of its companion aws_s3_bucket_*
+	// resources, every resource is read with the S3 call of its bucket configuration
+	Handler struct {
+		client       API
+		resourceType string
+		read         reader
+	}
+
+	// reader reads a bucket configuration from S3 in the terraform shape of its resource type
+	reader func(ctx context.Context, client API, bucket string) (map[string]interface{}, error)
+)
+
+// NewHandlers creates the handlers of the S3 bucket resource types
+func NewHandlers(client API) []registry.Handler {
+	return []registry.Handler{
+		&Handler{client: client, resourceType: ResourceType, read: readBucket},
+		&Handler{client: client, resourceType: ResourceTypePolicy, read: readPolicy},
+		&Handler{client: client, resourceType: ResourceTypeVersioning, read: readVersioning},
+		&Handler{client: client, resourceType: ResourceTypeEncryption, read: readEncryption},
+		&Handler{client: client, resourceType: ResourceTypePublicAccessBlock, read: readPublicAccessBlock},
+		&Handler{client: client, resourceType: ResourceTypeLifecycle, read: readLifecycle},
+	}
+}
+
+func (h *Handler) Types() []string {
+	return []string{h.resourceType}
+}
+
+// Normalize decodes the resources of the handler type, every resource is identified by its bucket name
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0, len(state[h.resourceType]))
+	for _, stateResource := range state[h.resourceType] {
+		attributes := make(map[string]interface{})
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &attributes); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+
+		values := make(map[string]interface{}, len(attributes))
+		for key, value := range attributes {
+			values[key] = value
+		}
+		if h.resourceType == ResourceType {
+			values["tags"] = map[string]string{}
+			if tags := utils.MergedTags(utils.StringMap(attributes["tags"]), utils.StringMap(attributes["tags_all"])); tags != nil {
+				values["tags"] = tags
+			}
+		}
+		resources = append(resources, &registry.Resource{
+			Type:    h.resourceType,
+			Address: stateResource.Address,
+			ID:      utils.StringValue(attributes["bucket"]),
+			Object:  attributes,
+			Values:  values,
+		})
+	}
+	return resources, nil
+}
+
+// Fetch reads the bucket configuration of every resource from S3, keyed by bucket name. The buckets that do not
+// exist any more are left out
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	awsResources := make(map[string]*registry.Resource, len(resources))
+	for _, bucket := range registry.IDs(resources) {
+		_, err := h.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			return nil, readError(bucket, err)
+		}
+
+		values, err := h.read(ctx, h.client, bucket)
+		if err != nil {
+			return nil, readError(bucket, err)
+		}
+		values["bucket"] = bucket
+		awsResources[bucket] = &registry.Resource{Type: h.resourceType, ID: bucket, Object: values, Values: values}
+	}
+	return awsResources, nil
+}
+
+// Compare compares a bucket configuration from S3 with the terraform state. Policies are compared by their canonical
+// form, so that formatting, key and statement order are not reported as drift
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	tfValues := tfResource.Object.(map[string]interface{})
+	awsValues := awsResource.Values
+	keys := utils.SortedKeys(awsValues)
+
+	differences := make([]*entities.Difference, 0)
+	for _, key := range keys {
+		switch key {
+		case "bucket":
+			continue
+		case "tags":
+			awsTags, _ := awsValues[key].(map[string]string)
+			tags, tagsAll := utils.StringMap(tfValues["tags"]), utils.StringMap(tfValues["tags_all"])
+			differences = append(differences, utils.TagDifferences(awsTags, tags, tagsAll, options.IncludeReservedTags)...)
+		case "policy":
+			// the policy of an aws_s3_bucket is managed by its aws_s3_bucket_policy when it is not set inline
+			tfPolicy := utils.StringValue(tfValues[key])
+			if h.resourceType == ResourceType && tfPolicy == "" {
+				continue
+			}
+			difference, err := utils.PolicyDifference(key, tfPolicy, utils.StringValue(awsValues[key]))
+			if err != nil {
+				return nil, err
+			}
+			if difference != nil {
+				differences = append(differences, difference)
+			}
+		case "rule":
+			if h.resourceType == ResourceTypeLifecycle {
+				differences = append(differences, lifecycleDifferences(awsValues[key], tfValues[key])...)
+				continue
+			}
+			differences = append(differences, utils.CompareNested(key, awsValues[key], tfValues[key])...)
+		default:
+			differences = append(differences, utils.CompareNested(key, awsValues[key], tfValues[key])...)
+		}
+	}
+	return differences, nil
+}
+
+// lifecycleDifferences compares the lifecycle rules by id, e.g. rule.expire-logs.expiration[0].days
+func lifecycleDifferences(awsValue, tfValue interface{}) []*entities.Difference {
+	byID := func(value interface{}) map[string]interface{} {
+		rules := make(map[string]interface{})
+		list, _ := value.([]interface{})
+		for _, rule := range list {
+			if rule, ok := rule.(map[string]interface{}); ok {
+				rules[utils.StringValue(rule["id"])] = rule
+			}
+		}
+		return rules
+	}
+	awsRules, tfRules := byID(awsValue), byID(tfValue)
+
+	differences := make([]*entities.Difference, 0)
+	for id, awsRule := range awsRules {
+		tfRule, ok := tfRules[id]
+		if !ok {
+			differences = append(differences, entities.NewDifference("rule."+id, nil, id))
+			continue
+		}
+		differences = append(differences, utils.CompareNested("rule."+id, awsRule, tfRule)...)
+	}
+	for id := range tfRules {
+		if _, ok := awsRules[id]; !ok {
+			differences = append(differences, entities.NewDifference("rule."+id, id, nil))
+		}
+	}
+	return differences
+}
+
+func readBucket(ctx context.Context, client API, bucket string) (map[string]interface{}, error) {
+	tags := make(map[string]string)
+	output, err := client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(bucket)})
+	if err != nil && !isErrorCode(err, "NoSuchTagSet") {
+		return nil, err
+	}
+	if output != nil {
+		for _, tag := range output.TagSet {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+
+	policy, err := readPolicy(ctx, client, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"tags": tags, "policy": policy["policy"]}, nil
+}
+
+func readPolicy(ctx context.Context, client API, bucket string) (map[string]interface{}, error) {
+	output, err := client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{Bucket: aws.String(bucket)})
+	if isErrorCode(err, "NoSuchBucketPolicy") {
+		return map[string]interface{}{"policy": ""}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"policy": aws.ToString(output.Policy)}, nil
+}
+
+func readVersioning(ctx context.Context, client API, bucket string) (map[string]interface{}, error) {
+	output, err := client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return nil, err
+	}
+	// a bucket that never had versioning enabled has no status, terraform names it Disabled
+	status := string(output.Status)
+	if status == "" {
+		status = "Disabled"
+	}
+	configuration := map[string]interface{}{"status": status}
+	if output.MFADelete != "" {
+		configuration["mfa_delete"] = string(output.MFADelete)
+	}
+	return map[string]interface{}{"versioning_configuration": []interface{}{configuration}}, nil
+}
+
+func readEncryption(ctx context.Context, client API, bucket string) (map[string]interface{}, error) {
+	rules := make([]interface{}, 0)
+	output, err := client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: aws.String(bucket)})
+	if err != nil && !isErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+		return nil, err
+	}
+	if output != nil && output.ServerSideEncryptionConfiguration != nil {
+		for _, rule := range output.ServerSideEncryptionConfiguration.Rules {
+			byDefault := make([]interface{}, 0, 1)
+			if rule.ApplyServerSideEncryptionByDefault != nil {
+				byDefault = append(byDefault, map[string]interface{}{
+					"sse_algorithm":     string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm),
+					"kms_master_key_id": aws.ToString(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID),
+				})
+			}
+			rules = append(rules, map[string]interface{}{
+				"apply_server_side_encryption_by_default": byDefault,
+				"bucket_key_enabled":                      aws.ToBool(rule.BucketKeyEnabled),
+			})
+		}
+	}
+	return map[string]interface{}{"rule": rules}, nil
+}
+
+func readPublicAccessBlock(ctx context.Context, client API, bucket string) (map[string]interface{}, error) {
+	configuration := &types.PublicAccessBlockConfiguration{}
+	output, err := client.GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{Bucket: aws.String(bucket)})
+	if err != nil && !isErrorCode(err, "NoSuchPublicAccessBlockConfiguration") {
+		return nil, err
+	}
+	if output != nil && output.PublicAccessBlockConfiguration != nil {
+		configuration = output.PublicAccessBlockConfiguration
+	}
+	return map[string]interface{}{
+		"block_public_acls":       aws.ToBool(configuration.BlockPublicAcls),
+		"block_public_policy":     aws.ToBool(configuration.BlockPublicPolicy),
+		"ignore_public_acls":      aws.ToBool(configuration.IgnorePublicAcls),
+		"restrict_public_buckets": aws.ToBool(configuration.RestrictPublicBuckets),
+	}, nil
+}
+
+// readLifecycle reads the lifecycle rules, the optional values are only set when S3 returns them
+func readLifecycle(ctx context.Context, client API, bucket string) (map[string]interface{}, error) {
+	rules := make([]interface{}, 0)
+	output, err := client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(bucket)})
+	if err != nil && !isErrorCode(err, "NoSuchLifecycleConfiguration") {
+		return nil, err
+	}
+	if output == nil {
+		return map[string]interface{}{"rule": rules}, nil
+	}
+
+	for _, rule := range output.Rules {
+		values := map[string]interface{}{
+			"id":     aws.ToString(rule.ID),
+			"status": string(rule.Status),
+		}
+		if filter := rule.Filter; filter != nil {
+			filterValues := make(map[string]interface{})
+			if filter.Prefix != nil {
+				filterValues["prefix"] = aws.ToString(filter.Prefix)
+			}
+			if filter.And != nil {
+				filterValues["and"] = []interface{}{map[string]interface{}{
+					"prefix": aws.ToString(filter.And.Prefix),
+					"tags":   tagValues(filter.And.Tags),
+				}}
+			}
+			values["filter"] = []interface{}{filterValues}
+		}
+		if expiration := rule.Expiration; expiration != nil {
+			expirationValues := make(map[string]interface{})
+			setOptional(expirationValues, "days", expiration.Days)
+			setOptional(expirationValues, "date", expiration.Date)
+			setOptional(expirationValues, "expired_object_delete_marker", expiration.ExpiredObjectDeleteMarker)
+			values["expiration"] = []interface{}{expirationValues}
+		}
+		transitions := make([]interface{}, 0, len(rule.Transitions))
+		for _, transition := range rule.Transitions {
+			transitionValues := map[string]interface{}{"storage_class": string(transition.StorageClass)}
+			setOptional(transitionValues, "days", transition.Days)
+			setOptional(transitionValues, "date", transition.Date)
+			transitions = append(transitions, transitionValues)
+		}
+		values["transition"] = transitions
+		if expiration := rule.NoncurrentVersionExpiration; expiration != nil {
+			expirationValues := make(map[string]interface{})
+			setOptional(expirationValues, "noncurrent_days", expiration.NoncurrentDays)
+			values["noncurrent_version_expiration"] = []interface{}{expirationValues}
+		}
+		if upload := rule.AbortIncompleteMultipartUpload; upload != nil {
+			values["abort_incomplete_multipart_upload"] = []interface{}{map[string]interface{}{
+				"days_after_initiation": aws.ToInt32(upload.DaysAfterInitiation),
+			}}
+		}
+		rules = append(rules, values)
+	}
+	return map[string]interface{}{"rule": rules}, nil
+}
+
+// setOptional sets the value of an optional field of the S3 API, dates are written like in the terraform state
+func setOptional(values map[string]interface{}, key string, value interface{}) {
+	switch v := value.(type) {
+	case *int32:
+		if v != nil {
+			values[key] = *v
+		}
+	case *bool:
+		if v != nil {
+			values[key] = *v
+		}
+	case *time.Time:
+		if v != nil {
+			values[key] = v.UTC().Format(time.RFC3339)
+		}
+	}
+}
+
+func tagValues(tags []types.Tag) map[string]interface{} {
+	values := make(map[string]interface{}, len(tags))
+	for _, tag := range tags {
+		values[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return values
+}
+
+// isErrorCode reports whether err is an S3 error with the code, S3 answers with an error when a bucket has no
+// configuration of a kind
+func isErrorCode(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
+
+func readError(bucket string, err error) error {
+	utils.Logger.Sugar().Errorf("failed to read s3 bucket %s: %v", bucket, err)
+	return &entities.CustomError{
+		StatusCode: http.StatusBadRequest,
+		Err:        fmt.Errorf("bucket %s: %w", bucket, err),
+	}
+}
@@ -0,0 +1,133 @@
+package s3bucket
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/registry/registrytest"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// localS3 is a local stand-in of the S3 API answering the bucket configuration calls, keyed by bucket and by the
+// subresource of the call, e.g. versioning. A configuration that is not set answers with its S3 error code
+type localS3 map[string]map[string]string
+
+func (l localS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	configurations, ok := l[strings.Trim(r.URL.Path, "/")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	for subresource := range r.URL.Query() {
+		if body, ok := configurations[subresource]; ok {
+			fmt.Fprint(w, body)
+			return
+		}
+	}
+	codes := map[string]string{
+		"tagging":           "NoSuchTagSet",
+		"policy":            "NoSuchBucketPolicy",
+		"encryption":        "ServerSideEncryptionConfigurationNotFoundError",
+		"publicAccessBlock": "NoSuchPublicAccessBlockConfiguration",
+		"lifecycle":         "NoSuchLifecycleConfiguration",
+	}
+	for subresource := range r.URL.Query() {
+		if code, ok := codes[subresource]; ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "<Error><Code>%s</Code><Message>not found</Message></Error>", code)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusBadRequest)
+}
+
+func TestS3BucketHandlers(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	server := httptest.NewServer(localS3{
+		"driftreport-logs": {
+			"tagging":    "<Tagging><TagSet><Tag><Key>Name</Key><Value>logs</Value></Tag><Tag><Key>Team</Key><Value>data</Value></Tag></TagSet></Tagging>",
+			"policy":     `{"Statement": [{"Action": "s3:*", "Condition": {"Bool": {"aws:SecureTransport": "false"}}, "Effect": "Deny", "Principal": "*", "Resource": "arn:aws:s3:::driftreport-logs/*", "Sid": "DenyInsecure"}], "Version": "2012-10-17"}`,
+			"versioning": "<VersioningConfiguration><Status>Suspended</Status></VersioningConfiguration>",
+			"encryption": "<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>AES256</SSEAlgorithm></ApplyServerSideEncryptionByDefault><BucketKeyEnabled>false</BucketKeyEnabled></Rule></ServerSideEncryptionConfiguration>",
+			"lifecycle":  "<LifecycleConfiguration><Rule><ID>expire-logs</ID><Filter><Prefix>logs/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>30</Days></Expiration><Transition><Days>14</Days><StorageClass>STANDARD_IA</StorageClass></Transition></Rule></LifecycleConfiguration>",
+		},
+	})
+	defer server.Close()
+
+	client := providers.NewS3Client(aws.Config{
+		Region:      "us-west-2",
+		Credentials: credentials.NewStaticCredentialsProvider("test", "test", ""),
+	}, server.URL)
+	state, err := registry.LoadState("../../testdata/s3.tfstate.json")
+
+	handlers := registry.New(NewHandlers(client)...)
+
+	Convey("a bucket tag from the provider default tags is compared with tags_all", t, func() {
+		So(err, ShouldBeNil)
+		results, err := registrytest.Check(handlers, ResourceType, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_s3_bucket.logs"].Differences
+		So(results["aws_s3_bucket.deleted"].Removed, ShouldBeTrue)
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "default_tags.Team")
+		So(differences[0].Actual, ShouldEqual, "data")
+	})
+
+	Convey("a bucket policy only differing in formatting and key order is not reported", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypePolicy, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_s3_bucket_policy.logs"].Differences
+		So(differences, ShouldBeEmpty)
+	})
+
+	Convey("suspended versioning is reported as drift", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypeVersioning, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_s3_bucket_versioning.logs"].Differences
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "versioning_configuration[0].status")
+		So(differences[0].Expected, ShouldEqual, "Enabled")
+		So(differences[0].Actual, ShouldEqual, "Suspended")
+	})
+
+	Convey("an unchanged encryption configuration is not reported", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypeEncryption, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_s3_bucket_server_side_encryption_configuration.logs"].Differences
+		So(differences, ShouldBeEmpty)
+	})
+
+	Convey("a removed public access block reports every setting", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypePublicAccessBlock, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_s3_bucket_public_access_block.logs"].Differences
+		So(differences, ShouldHaveLength, 4)
+		So(differences[0].Attribute, ShouldEqual, "block_public_acls")
+		So(differences[0].Actual, ShouldEqual, "false")
+	})
+
+	Convey("lifecycle rules are compared by id", t, func() {
+		results, err := registrytest.Check(handlers, ResourceTypeLifecycle, state, &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		differences := results["aws_s3_bucket_lifecycle_configuration.logs"].Differences
+		So(differences, ShouldHaveLength, 1)
+		So(differences[0].Attribute, ShouldEqual, "rule.expire-logs.transition[0].days")
+		So(differences[0].Expected, ShouldEqual, "7")
+		So(differences[0].Actual, ShouldEqual, "14")
+	})
+}
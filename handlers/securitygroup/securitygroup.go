@@ -0,0 +1,172 @@
+package securitygroup
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+)
+
+const (
+	ResourceType     = "aws_security_group"
+	RuleResourceType = "aws_security_group_rule"
+)
+
+// Handler checks the drift of aws_security_group resources, including the rules of aws_security_group_rule resources
+type Handler struct {
+	awsProvider providers.AWSProvider
+}
+
+func NewHandler(awsProvider providers.AWSProvider) *Handler {
+	return &Handler{awsProvider: awsProvider}
+}
+
+func (h *Handler) Types() []string {
+	return []string{ResourceType, RuleResourceType}
+}
+
+// Normalize decodes the security groups of the terraform state. The rules of aws_security_group_rule resources are
+// added to the group they belong to
+func (h *Handler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	groups := make([]*entities.SecurityGroup, 0, len(state[ResourceType]))
+	groupMap := make(map[string]*entities.SecurityGroup)
+	for _, stateResource := range state[ResourceType] {
+		var tfGroup entities.TerraformSecurityGroup
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &tfGroup); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+		group := &entities.SecurityGroup{
+			Address:     stateResource.Address,
+			GroupID:     tfGroup.ID,
+			Name:        tfGroup.Name,
+			Description: tfGroup.Description,
+			VpcID:       tfGroup.VpcID,
+			Ingress:     make([]string, 0),
+			Egress:      make([]string, 0),
+			Tags:        tfGroup.Tags,
+			TagsAll:     tfGroup.TagsAll,
+		}
+		for _, rule := range tfGroup.Ingress {
+			group.Ingress = utils.AppendUnique(group.Ingress, permissionKeys(rule, tfGroup.ID)...)
+		}
+		for _, rule := range tfGroup.Egress {
+			group.Egress = utils.AppendUnique(group.Egress, permissionKeys(rule, tfGroup.ID)...)
+		}
+		groups = append(groups, group)
+		groupMap[tfGroup.ID] = group
+	}
+
+	// the group state usually lists the standalone rules too after a refresh, so keys are only added once
+	for _, stateResource := range state[RuleResourceType] {
+		var rule entities.TerraformSecurityGroupRule
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &rule); err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+		group, ok := groupMap[rule.SecurityGroupID]
+		if !ok {
+			utils.Logger.Sugar().Warnf("security group %s of aws_security_group_rule is not in the terraform state", rule.SecurityGroupID)
+			continue
+		}
+		if rule.Type == "egress" {
+			group.Egress = utils.AppendUnique(group.Egress, permissionKeys(&rule, rule.SecurityGroupID)...)
+		} else {
+			group.Ingress = utils.AppendUnique(group.Ingress, permissionKeys(&rule, rule.SecurityGroupID)...)
+		}
+	}
+
+	resources := make([]*registry.Resource, 0, len(groups))
+	for _, group := range groups {
+		resources = append(resources, newResource(group))
+	}
+	return resources, nil
+}
+
+// Fetch gets the security groups and their rules from AWS
+func (h *Handler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	groupMap, err := h.awsProvider.GetSecurityGroups(ctx, registry.IDs(resources))
+	if err != nil {
+		return nil, err
+	}
+	awsResources := make(map[string]*registry.Resource, len(groupMap))
+	for id, group := range groupMap {
+		awsResources[id] = newResource(group)
+	}
+	return awsResources, nil
+}
+
+// Compare compares a security group from AWS with the terraform state, a rule opened in the console is reported as an
+// added ingress or egress permission, e.g. ingress.tcp:22-22:0.0.0.0/0
+func (h *Handler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	tfGroup, awsGroup := tfResource.Object.(*entities.SecurityGroup), awsResource.Object.(*entities.SecurityGroup)
+	differences := make([]*entities.Difference, 0)
+	if awsGroup.Name != tfGroup.Name {
+		differences = append(differences, entities.NewDifference("name", tfGroup.Name, awsGroup.Name))
+	}
+	if awsGroup.Description != tfGroup.Description {
+		differences = append(differences, entities.NewDifference("description", tfGroup.Description, awsGroup.Description))
+	}
+	if awsGroup.VpcID != tfGroup.VpcID {
+		differences = append(differences, entities.NewDifference("vpc_id", tfGroup.VpcID, awsGroup.VpcID))
+	}
+	differences = append(differences, utils.SetDifferences("ingress", awsGroup.Ingress, tfGroup.Ingress)...)
+	differences = append(differences, utils.SetDifferences("egress", awsGroup.Egress, tfGroup.Egress)...)
+	differences = append(differences, utils.TagDifferences(awsGroup.Tags, tfGroup.Tags, tfGroup.TagsAll, options.IncludeReservedTags)...)
+	return differences, nil
+}
+
+// permissionKeys expands a terraform rule to one permission key per source, like AWS lists security group rules
+func permissionKeys(rule *entities.TerraformSecurityGroupRule, groupID string) []string {
+	sources := make([]string, 0)
+	sources = append(sources, rule.CidrBlocks...)
+	sources = append(sources, rule.Ipv6CidrBlocks...)
+	sources = append(sources, rule.PrefixListIDs...)
+	for _, group := range rule.SecurityGroups {
+		// groups of other accounts are written as account-id/sg-id
+		sources = append(sources, group[strings.LastIndex(group, "/")+1:])
+	}
+	if rule.SourceSecurityGroupID != "" {
+		sources = append(sources, rule.SourceSecurityGroupID)
+	}
+	if rule.Self {
+		sources = append(sources, groupID)
+	}
+
+	keys := make([]string, 0, len(sources))
+	for _, source := range sources {
+		keys = append(keys, entities.PermissionKey(rule.Protocol, rule.FromPort, rule.ToPort, source))
+	}
+	return keys
+}
+
+// newResource wraps a security group of either side, the custom rules see the tags merged with the default_tags
+func newResource(group *entities.SecurityGroup) *registry.Resource {
+	tags := utils.MergedTags(group.Tags, group.TagsAll)
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	return &registry.Resource{
+		Type:    ResourceType,
+		Address: group.Address,
+		ID:      group.GroupID,
+		Object:  group,
+		Values: map[string]interface{}{
+			"name":        group.Name,
+			"description": group.Description,
+			"vpc_id":      group.VpcID,
+			"ingress":     group.Ingress,
+			"egress":      group.Egress,
+			"tags":        tags,
+		},
+	}
+}
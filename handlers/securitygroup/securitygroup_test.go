@@ -0,0 +1,55 @@
+package securitygroup
+
+import (
+	"testing"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSecurityGroupHandler(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	handler := NewHandler(nil)
+	state, err := registry.LoadState("../../testdata/security_groups.tfstate.json")
+
+	Convey("load security groups and standalone rules from the terraform state", t, func() {
+		So(err, ShouldBeNil)
+		resources, err := handler.Normalize(state)
+		So(err, ShouldBeNil)
+		So(registry.IDs(resources), ShouldResemble, []string{"sg-091fde8327f3fe99a"})
+
+		group := resources[0].Object.(*entities.SecurityGroup)
+		So(resources[0].Address, ShouldEqual, "aws_security_group.example_sg")
+		So(group.Ingress, ShouldResemble, []string{"tcp:443-443:10.0.0.0/16", "tcp:8080-8080:sg-091fde8327f3fe99a"})
+		So(group.Egress, ShouldResemble, []string{"all:all:0.0.0.0/0"})
+	})
+
+	Convey("an opened port is reported as drift", t, func() {
+		resources, err := handler.Normalize(state)
+		So(err, ShouldBeNil)
+
+		awsGroup := &entities.SecurityGroup{
+			GroupID:     "sg-091fde8327f3fe99a",
+			Name:        "example-security-group",
+			Description: "Allow HTTPS",
+			VpcID:       "vpc-0a1b2c3d",
+			Ingress: []string{
+				entities.PermissionKey("tcp", 443, 443, "10.0.0.0/16"),
+				entities.PermissionKey("tcp", 8080, 8080, "sg-091fde8327f3fe99a"),
+				entities.PermissionKey("tcp", 22, 22, "0.0.0.0/0"),
+			},
+			Egress: []string{entities.PermissionKey("-1", -1, -1, "0.0.0.0/0")},
+			Tags:   map[string]string{"Name": "example"},
+		}
+		differences, err := handler.Compare(resources[0], newResource(awsGroup), &entities.ReportOptions{})
+		So(err, ShouldBeNil)
+		So(len(differences), ShouldEqual, 1)
+		So(differences[0].Attribute, ShouldEqual, "ingress.tcp:22-22:0.0.0.0/0")
+		So(differences[0].Kind, ShouldEqual, entities.ChangeKindAdded)
+		So(entities.DefaultDriftPolicy().Classify(ResourceType, differences[0]), ShouldEqual, entities.SeverityCritical)
+	})
+}
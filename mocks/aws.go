@@ -2,22 +2,112 @@ package mocks
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strings"
 
-	"github.com/driftreport/entities"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
 	"github.com/driftreport/providers"
 )
 
 type (
-	MockAWSProvider struct {
+	// MockEC2Client answers like EC2 for an account without resources: a Describe call by ids fails with the
+	// InvalidXxx.NotFound error of EC2, a call by filters finds nothing
+	MockEC2Client struct {
 	}
 )
 
+// NewAWSProvider creates the AWS provider over a MockEC2Client
 func NewAWSProvider() providers.AWSProvider {
-	return &MockAWSProvider{}
+	return providers.NewAWSProviderWithClient("us-east-1", &MockEC2Client{})
 }
 
-func (s *MockAWSProvider) GetEC2Instances(ctx context.Context, instanceIds []string) (map[string]*entities.EC2Instance, error) {
-	log.Printf("get ec2 instances by %s", instanceIds)
-	return map[string]*entities.EC2Instance{}, nil
+func (s *MockEC2Client) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	log.Printf("describe instances by %s %s", params.InstanceIds, filterValues(params.Filters))
+	return &ec2.DescribeInstancesOutput{}, notFound("InvalidInstanceID.NotFound", params.InstanceIds)
+}
+
+func (s *MockEC2Client) DescribeInstanceCreditSpecifications(ctx context.Context, params *ec2.DescribeInstanceCreditSpecificationsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceCreditSpecificationsOutput, error) {
+	log.Printf("describe instance credit specifications by %s %s", params.InstanceIds, filterValues(params.Filters))
+	return &ec2.DescribeInstanceCreditSpecificationsOutput{}, notFound("InvalidInstanceID.NotFound", params.InstanceIds)
+}
+
+func (s *MockEC2Client) DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	log.Printf("describe volumes by %s %s", params.VolumeIds, filterValues(params.Filters))
+	return &ec2.DescribeVolumesOutput{}, notFound("InvalidVolume.NotFound", params.VolumeIds)
+}
+
+func (s *MockEC2Client) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	log.Printf("describe security groups by %s %s", params.GroupIds, filterValues(params.Filters))
+	return &ec2.DescribeSecurityGroupsOutput{}, notFound("InvalidGroup.NotFound", params.GroupIds)
+}
+
+func (s *MockEC2Client) DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	log.Printf("describe security group rules by %s %s", params.SecurityGroupRuleIds, filterValues(params.Filters))
+	return &ec2.DescribeSecurityGroupRulesOutput{}, notFound("InvalidSecurityGroupRuleId.NotFound", params.SecurityGroupRuleIds)
+}
+
+func (s *MockEC2Client) DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	log.Printf("describe vpcs by %s %s", params.VpcIds, filterValues(params.Filters))
+	return &ec2.DescribeVpcsOutput{}, notFound("InvalidVpcID.NotFound", params.VpcIds)
+}
+
+func (s *MockEC2Client) DescribeVpcAttribute(ctx context.Context, params *ec2.DescribeVpcAttributeInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcAttributeOutput, error) {
+	log.Printf("describe vpc attribute %s of %s", params.Attribute, *params.VpcId)
+	return nil, notFound("InvalidVpcID.NotFound", []string{*params.VpcId})
+}
+
+func (s *MockEC2Client) DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	log.Printf("describe subnets by %s %s", params.SubnetIds, filterValues(params.Filters))
+	return &ec2.DescribeSubnetsOutput{}, notFound("InvalidSubnetID.NotFound", params.SubnetIds)
+}
+
+func (s *MockEC2Client) DescribeRouteTables(ctx context.Context, params *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+	log.Printf("describe route tables by %s %s", params.RouteTableIds, filterValues(params.Filters))
+	return &ec2.DescribeRouteTablesOutput{}, notFound("InvalidRouteTableID.NotFound", params.RouteTableIds)
+}
+
+func (s *MockEC2Client) DescribeInternetGateways(ctx context.Context, params *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+	log.Printf("describe internet gateways by %s %s", params.InternetGatewayIds, filterValues(params.Filters))
+	return &ec2.DescribeInternetGatewaysOutput{}, notFound("InvalidInternetGatewayID.NotFound", params.InternetGatewayIds)
+}
+
+func (s *MockEC2Client) DescribeNatGateways(ctx context.Context, params *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+	log.Printf("describe nat gateways by %s %s", params.NatGatewayIds, filterValues(params.Filter))
+	return &ec2.DescribeNatGatewaysOutput{}, notFound("NatGatewayNotFound", params.NatGatewayIds)
+}
+
+func (s *MockEC2Client) DescribeLaunchTemplates(ctx context.Context, params *ec2.DescribeLaunchTemplatesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplatesOutput, error) {
+	log.Printf("describe launch templates by %s %s", params.LaunchTemplateIds, filterValues(params.Filters))
+	return &ec2.DescribeLaunchTemplatesOutput{}, notFound("InvalidLaunchTemplateId.NotFound", params.LaunchTemplateIds)
+}
+
+func (s *MockEC2Client) DescribeLaunchTemplateVersions(ctx context.Context, params *ec2.DescribeLaunchTemplateVersionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplateVersionsOutput, error) {
+	log.Printf("describe launch template %v versions %s", params.LaunchTemplateId, params.Versions)
+	if params.LaunchTemplateId != nil {
+		return nil, notFound("InvalidLaunchTemplateId.NotFound", []string{*params.LaunchTemplateId})
+	}
+	return &ec2.DescribeLaunchTemplateVersionsOutput{}, nil
+}
+
+// notFound is the error EC2 answers a Describe call by ids with when an id does not exist, nil without ids
+func notFound(code string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return &smithy.GenericAPIError{
+		Code:    code,
+		Message: fmt.Sprintf("The ids '%s' do not exist", strings.Join(ids, ", ")),
+	}
+}
+
+// filterValues prints the values of the filters of a Describe call
+func filterValues(filters []types.Filter) string {
+	values := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		values = append(values, fmt.Sprintf("%s=%s", *filter.Name, strings.Join(filter.Values, ",")))
+	}
+	return strings.Join(values, " ")
 }
@@ -2,9 +2,12 @@ package providers
 
 import (
 	"context"
-	"errors"
 	"net/http"
+	"sort"
+	"strings"
+	"unicode"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
@@ -15,60 +18,122 @@ import (
 type (
 	AWSProvider interface {
 		GetEC2Instances(ctx context.Context, instanceIDs []string) (map[string]*entities.EC2Instance, error)
+		GetSecurityGroups(ctx context.Context, groupIDs []string) (map[string]*entities.SecurityGroup, error)
+		GetEBSVolumes(ctx context.Context, volumeIDs []string) (map[string]*entities.EBSVolume, error)
+		GetVpcs(ctx context.Context, vpcIDs []string) (map[string]map[string]interface{}, error)
+		GetSubnets(ctx context.Context, subnetIDs []string) (map[string]map[string]interface{}, error)
+		GetRouteTables(ctx context.Context, routeTableIDs []string) (map[string]map[string]interface{}, error)
+		GetInternetGateways(ctx context.Context, gatewayIDs []string) (map[string]map[string]interface{}, error)
+		GetNatGateways(ctx context.Context, gatewayIDs []string) (map[string]map[string]interface{}, error)
+		GetLaunchTemplates(ctx context.Context, templateIDs []string) (map[string]map[string]interface{}, error)
+		GetLaunchTemplateVersion(ctx context.Context, templateID, version string) (map[string]interface{}, error)
 	}
 
 	AppAWSProvider struct {
 		awsRegion string
-		client    *ec2.Client
+		client    EC2API
+	}
+
+	// EC2API is the part of the EC2 client the provider calls
+	EC2API interface {
+		DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+		DescribeInstanceCreditSpecifications(ctx context.Context, params *ec2.DescribeInstanceCreditSpecificationsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceCreditSpecificationsOutput, error)
+		DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error)
+		DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+		DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error)
+		DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error)
+		DescribeVpcAttribute(ctx context.Context, params *ec2.DescribeVpcAttributeInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcAttributeOutput, error)
+		DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+		DescribeRouteTables(ctx context.Context, params *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error)
+		DescribeInternetGateways(ctx context.Context, params *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error)
+		DescribeNatGateways(ctx context.Context, params *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error)
+		DescribeLaunchTemplates(ctx context.Context, params *ec2.DescribeLaunchTemplatesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplatesOutput, error)
+		DescribeLaunchTemplateVersions(ctx context.Context, params *ec2.DescribeLaunchTemplateVersionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplateVersionsOutput, error)
 	}
 )
 
+// maxFilterValues is the number of values EC2 takes in a filter
+const maxFilterValues = 200
+
 func NewAWSProvider(
 	awsRegion string,
 ) (AWSProvider, error) {
-	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(awsRegion))
+	cfg, err := NewAWSConfig(awsRegion)
 	if err != nil {
-		utils.Logger.Sugar().Errorf("failed to load default config: %v", err)
-		return nil, &entities.CustomError{
-			StatusCode: http.StatusUnauthorized,
-			Err:        err,
-		}
+		return nil, err
 	}
 
-	client := ec2.NewFromConfig(cfg)
+	return NewAWSProviderWithClient(awsRegion, ec2.NewFromConfig(cfg)), nil
+}
+
+// NewAWSProviderWithClient creates a provider calling EC2 through client, e.g. a mock answering like EC2 does
+func NewAWSProviderWithClient(awsRegion string, client EC2API) AWSProvider {
 	return &AppAWSProvider{
 		awsRegion: awsRegion,
 		client:    client,
-	}, nil
-}
-
-//GetEC2Instances get EC2 instance from AWS account
-func (a *AppAWSProvider) GetEC2Instances(ctx context.Context, instanceIDs []string) (map[string]*entities.EC2Instance, error) {
-	input := &ec2.DescribeInstancesInput{
-		InstanceIds: instanceIDs,
 	}
+}
 
-	result, err := a.client.DescribeInstances(ctx, input)
+// NewAWSConfig loads the AWS credentials and settings of the environment, the clients of every service are created from it
+func NewAWSConfig(awsRegion string) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(awsRegion))
 	if err != nil {
-		utils.Logger.Sugar().Errorf("failed to describe instances: %v", err)
-		return nil, &entities.CustomError{
-			StatusCode: http.StatusBadRequest,
+		utils.Logger.Sugar().Errorf("failed to load default config: %v", err)
+		return cfg, &entities.CustomError{
+			StatusCode: http.StatusUnauthorized,
 			Err:        err,
 		}
 	}
+	return cfg, nil
+}
 
-	if len(result.Reservations) == 0 || len(result.Reservations[0].Instances) == 0 {
-		utils.Logger.Sugar().Warn("no instances found")
-		return nil, &entities.CustomError{
-			StatusCode: http.StatusNotFound,
-			Err:        errors.New("no instances found"),
+// GetEC2Instances gets the EC2 instances from AWS account, the instances that no longer exist or are terminated are
+// left out
+func (a *AppAWSProvider) GetEC2Instances(ctx context.Context, instanceIDs []string) (map[string]*entities.EC2Instance, error) {
+	instanceMap := make(map[string]*entities.EC2Instance)
+	typeInstances := make([]types.Instance, 0)
+	for _, filters := range idFilters("instance-id", instanceIDs) {
+		paginator := ec2.NewDescribeInstancesPaginator(a.client, &ec2.DescribeInstancesInput{Filters: filters})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				utils.Logger.Sugar().Errorf("failed to describe instances: %v", err)
+				return nil, &entities.CustomError{
+					StatusCode: http.StatusBadRequest,
+					Err:        err,
+				}
+			}
+			for _, reservation := range page.Reservations {
+				for _, instance := range reservation.Instances {
+					if instance.State != nil && instance.State.Name == types.InstanceStateNameTerminated {
+						continue
+					}
+					typeInstances = append(typeInstances, instance)
+				}
+			}
 		}
 	}
 
-	instanceMap := make(map[string]*entities.EC2Instance)
-	typeInstances := make([]types.Instance, 0)
-	for _, res := range result.Reservations {
-		typeInstances = append(typeInstances, res.Instances...)
+	// block device details such as size and type are only returned by DescribeVolumes
+	volumeIDs := make([]string, 0)
+	burstableIDs := make([]string, 0)
+	for _, instance := range typeInstances {
+		for _, mapping := range instance.BlockDeviceMappings {
+			if mapping.Ebs != nil {
+				volumeIDs = append(volumeIDs, aws.ToString(mapping.Ebs.VolumeId))
+			}
+		}
+		if isBurstable(string(instance.InstanceType)) {
+			burstableIDs = append(burstableIDs, aws.ToString(instance.InstanceId))
+		}
+	}
+	volumes, err := a.describeVolumes(ctx, volumeIDs)
+	if err != nil {
+		return nil, err
+	}
+	creditSpecifications, err := a.describeCreditSpecifications(ctx, burstableIDs)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, instance := range typeInstances {
@@ -84,12 +149,252 @@ func (a *AppAWSProvider) GetEC2Instances(ctx context.Context, instanceIDs []stri
 			tags[*tag.Key] = *tag.Value
 		}
 
+		blocks := blockDevices(instance, volumes)
+		blocks["network_interface"] = networkInterfaces(instance)
+		if instance.MetadataOptions != nil {
+			blocks["metadata_options"] = []interface{}{map[string]interface{}{
+				"http_endpoint":               string(instance.MetadataOptions.HttpEndpoint),
+				"http_protocol_ipv6":          string(instance.MetadataOptions.HttpProtocolIpv6),
+				"http_put_response_hop_limit": aws.ToInt32(instance.MetadataOptions.HttpPutResponseHopLimit),
+				"http_tokens":                 string(instance.MetadataOptions.HttpTokens),
+				"instance_metadata_tags":      string(instance.MetadataOptions.InstanceMetadataTags),
+			}}
+		}
+		if credits, ok := creditSpecifications[id]; ok {
+			blocks["credit_specification"] = []interface{}{map[string]interface{}{
+				"cpu_credits": credits,
+			}}
+		}
+
 		instanceMap[id] = &entities.EC2Instance{
 			InstanceType:   string(instance.InstanceType),
 			SecurityGroups: sgs,
 			Tags:           tags,
+			Blocks:         blocks,
+			ImageID:        aws.ToString(instance.ImageId),
+			KeyName:        aws.ToString(instance.KeyName),
 		}
 	}
 
 	return instanceMap, nil
 }
+
+// GetEBSVolumes gets the EBS volumes and the instances they are attached to from AWS account
+func (a *AppAWSProvider) GetEBSVolumes(ctx context.Context, volumeIDs []string) (map[string]*entities.EBSVolume, error) {
+	volumes, err := a.describeVolumes(ctx, volumeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeMap := make(map[string]*entities.EBSVolume)
+	for id, volume := range volumes {
+		attachments := make(map[string]string)
+		for _, attachment := range volume.Attachments {
+			attachments[aws.ToString(attachment.InstanceId)] = aws.ToString(attachment.Device)
+		}
+		volumeMap[id] = &entities.EBSVolume{
+			VolumeID:         id,
+			AvailabilityZone: aws.ToString(volume.AvailabilityZone),
+			Size:             aws.ToInt32(volume.Size),
+			Type:             string(volume.VolumeType),
+			Iops:             aws.ToInt32(volume.Iops),
+			Throughput:       aws.ToInt32(volume.Throughput),
+			Encrypted:        aws.ToBool(volume.Encrypted),
+			KmsKeyID:         aws.ToString(volume.KmsKeyId),
+			Tags:             tagMap(volume.Tags),
+			Attachments:      attachments,
+		}
+	}
+	return volumeMap, nil
+}
+
+// describeVolumes gets the EBS volumes keyed by volume id, the volumes that no longer exist are left out
+func (a *AppAWSProvider) describeVolumes(ctx context.Context, volumeIDs []string) (map[string]types.Volume, error) {
+	volumes := make(map[string]types.Volume)
+	for _, filters := range idFilters("volume-id", volumeIDs) {
+		paginator := ec2.NewDescribeVolumesPaginator(a.client, &ec2.DescribeVolumesInput{Filters: filters})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				utils.Logger.Sugar().Errorf("failed to describe volumes: %v", err)
+				return nil, &entities.CustomError{
+					StatusCode: http.StatusBadRequest,
+					Err:        err,
+				}
+			}
+			for _, volume := range page.Volumes {
+				volumes[aws.ToString(volume.VolumeId)] = volume
+			}
+		}
+	}
+	return volumes, nil
+}
+
+// describeCreditSpecifications gets the cpu credit option (standard or unlimited) of burstable instances
+func (a *AppAWSProvider) describeCreditSpecifications(ctx context.Context, instanceIDs []string) (map[string]string, error) {
+	credits := make(map[string]string)
+	for _, filters := range idFilters("instance-id", instanceIDs) {
+		paginator := ec2.NewDescribeInstanceCreditSpecificationsPaginator(a.client, &ec2.DescribeInstanceCreditSpecificationsInput{
+			Filters: filters,
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				utils.Logger.Sugar().Errorf("failed to describe instance credit specifications: %v", err)
+				return nil, &entities.CustomError{
+					StatusCode: http.StatusBadRequest,
+					Err:        err,
+				}
+			}
+			for _, spec := range page.InstanceCreditSpecifications {
+				credits[aws.ToString(spec.InstanceId)] = aws.ToString(spec.CpuCredits)
+			}
+		}
+	}
+	return credits, nil
+}
+
+// isBurstable tells whether an instance type is of a burstable family, e.g. t3 or t4g but not trn1, from the family
+// before the dot
+func isBurstable(instanceType string) bool {
+	family, _, _ := strings.Cut(instanceType, ".")
+	return len(family) > 1 && family[0] == 't' && unicode.IsDigit(rune(family[1]))
+}
+
+// blockDevices maps the instance block devices to the terraform root_block_device and ebs_block_device blocks
+func blockDevices(instance types.Instance, volumes map[string]types.Volume) map[string]interface{} {
+	rootDevices := make([]interface{}, 0)
+	ebsDevices := make([]interface{}, 0)
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs == nil {
+			continue
+		}
+		device := map[string]interface{}{
+			"device_name":           aws.ToString(mapping.DeviceName),
+			"volume_id":             aws.ToString(mapping.Ebs.VolumeId),
+			"delete_on_termination": aws.ToBool(mapping.Ebs.DeleteOnTermination),
+		}
+		if volume, ok := volumes[aws.ToString(mapping.Ebs.VolumeId)]; ok {
+			device["volume_size"] = aws.ToInt32(volume.Size)
+			device["volume_type"] = string(volume.VolumeType)
+			device["iops"] = aws.ToInt32(volume.Iops)
+			device["throughput"] = aws.ToInt32(volume.Throughput)
+			device["encrypted"] = aws.ToBool(volume.Encrypted)
+			device["kms_key_id"] = aws.ToString(volume.KmsKeyId)
+		}
+		if aws.ToString(mapping.DeviceName) == aws.ToString(instance.RootDeviceName) {
+			rootDevices = append(rootDevices, device)
+		} else {
+			ebsDevices = append(ebsDevices, device)
+		}
+	}
+
+	// terraform keeps ebs_block_device as a set, sorted by device name on both sides to compare it element by element
+	return map[string]interface{}{
+		"root_block_device": rootDevices,
+		"ebs_block_device":  utils.SortBlocks(ebsDevices, "device_name"),
+	}
+}
+
+// networkInterfaces maps the attached network interfaces to the terraform network_interface block, by device index
+func networkInterfaces(instance types.Instance) []interface{} {
+	attached := make([]types.InstanceNetworkInterface, 0)
+	for _, networkInterface := range instance.NetworkInterfaces {
+		if networkInterface.Attachment != nil {
+			attached = append(attached, networkInterface)
+		}
+	}
+	sort.Slice(attached, func(i, j int) bool {
+		return aws.ToInt32(attached[i].Attachment.DeviceIndex) < aws.ToInt32(attached[j].Attachment.DeviceIndex)
+	})
+
+	interfaces := make([]interface{}, 0)
+	for _, networkInterface := range attached {
+		interfaces = append(interfaces, map[string]interface{}{
+			"device_index":          aws.ToInt32(networkInterface.Attachment.DeviceIndex),
+			"network_card_index":    aws.ToInt32(networkInterface.Attachment.NetworkCardIndex),
+			"network_interface_id":  aws.ToString(networkInterface.NetworkInterfaceId),
+			"delete_on_termination": aws.ToBool(networkInterface.Attachment.DeleteOnTermination),
+		})
+	}
+	return interfaces
+}
+
+// GetSecurityGroups gets the security groups and their ingress and egress rules from AWS account, the groups that no
+// longer exist are left out
+func (a *AppAWSProvider) GetSecurityGroups(ctx context.Context, groupIDs []string) (map[string]*entities.SecurityGroup, error) {
+	groupMap := make(map[string]*entities.SecurityGroup)
+	for _, filters := range idFilters("group-id", groupIDs) {
+		groupPaginator := ec2.NewDescribeSecurityGroupsPaginator(a.client, &ec2.DescribeSecurityGroupsInput{Filters: filters})
+		for groupPaginator.HasMorePages() {
+			page, err := groupPaginator.NextPage(ctx)
+			if err != nil {
+				utils.Logger.Sugar().Errorf("failed to describe security groups: %v", err)
+				return nil, &entities.CustomError{
+					StatusCode: http.StatusBadRequest,
+					Err:        err,
+				}
+			}
+			for _, group := range page.SecurityGroups {
+				groupMap[aws.ToString(group.GroupId)] = &entities.SecurityGroup{
+					GroupID:     aws.ToString(group.GroupId),
+					Name:        aws.ToString(group.GroupName),
+					Description: aws.ToString(group.Description),
+					VpcID:       aws.ToString(group.VpcId),
+					Ingress:     make([]string, 0),
+					Egress:      make([]string, 0),
+					Tags:        tagMap(group.Tags),
+				}
+			}
+		}
+
+		// DescribeSecurityGroupRules returns one rule per source, which maps directly to a permission key
+		rulePaginator := ec2.NewDescribeSecurityGroupRulesPaginator(a.client, &ec2.DescribeSecurityGroupRulesInput{Filters: filters})
+		for rulePaginator.HasMorePages() {
+			page, err := rulePaginator.NextPage(ctx)
+			if err != nil {
+				utils.Logger.Sugar().Errorf("failed to describe security group rules: %v", err)
+				return nil, &entities.CustomError{
+					StatusCode: http.StatusBadRequest,
+					Err:        err,
+				}
+			}
+			for _, rule := range page.SecurityGroupRules {
+				group, ok := groupMap[aws.ToString(rule.GroupId)]
+				if !ok {
+					continue
+				}
+				var source string
+				switch {
+				case rule.CidrIpv4 != nil:
+					source = aws.ToString(rule.CidrIpv4)
+				case rule.CidrIpv6 != nil:
+					source = aws.ToString(rule.CidrIpv6)
+				case rule.PrefixListId != nil:
+					source = aws.ToString(rule.PrefixListId)
+				case rule.ReferencedGroupInfo != nil:
+					source = aws.ToString(rule.ReferencedGroupInfo.GroupId)
+				}
+				key := entities.PermissionKey(aws.ToString(rule.IpProtocol), aws.ToInt32(rule.FromPort), aws.ToInt32(rule.ToPort), source)
+				if aws.ToBool(rule.IsEgress) {
+					group.Egress = append(group.Egress, key)
+				} else {
+					group.Ingress = append(group.Ingress, key)
+				}
+			}
+		}
+	}
+
+	return groupMap, nil
+}
+
+// idFilters filters the Describe calls on resource ids, maxFilterValues ids at a time. The ids parameter of the calls
+// fails the whole call with InvalidXxx.NotFound when one of the ids no longer exists, a filter leaves it out instead
+func idFilters(name string, ids []string) [][]types.Filter {
+	filters := make([][]types.Filter, 0, len(ids)/maxFilterValues+1)
+	for start := 0; start < len(ids); start += maxFilterValues {
+		end := min(start+maxFilterValues, len(ids))
+		filters = append(filters, []types.Filter{{Name: aws.String(name), Values: ids[start:end]}})
+	}
+	return filters
+}
@@ -0,0 +1,80 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// The clients of the AWS services other than EC2 are used by their handlers directly, through the part of the
+// client API the handler reads
+
+// NewS3Client creates the S3 client. An endpoint points it to an S3 compatible service, e.g. a local stand-in, which
+// is addressed with path style bucket URLs
+func NewS3Client(cfg aws.Config, endpoint string) *s3.Client {
+	return s3.NewFromConfig(cfg, func(options *s3.Options) {
+		if endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
+			options.UsePathStyle = true
+		}
+	})
+}
+
+// NewIAMClient creates the IAM client
+func NewIAMClient(cfg aws.Config) *iam.Client {
+	return iam.NewFromConfig(cfg)
+}
+
+// NewAutoScalingClient creates the Auto Scaling client
+func NewAutoScalingClient(cfg aws.Config) *autoscaling.Client {
+	return autoscaling.NewFromConfig(cfg)
+}
+
+// NewRDSClient creates the RDS client
+func NewRDSClient(cfg aws.Config) *rds.Client {
+	return rds.NewFromConfig(cfg)
+}
+
+// NewELBClient creates the Elastic Load Balancing v2 client
+func NewELBClient(cfg aws.Config) *elasticloadbalancingv2.Client {
+	return elasticloadbalancingv2.NewFromConfig(cfg)
+}
+
+// NewLambdaClient creates the Lambda client
+func NewLambdaClient(cfg aws.Config) *lambda.Client {
+	return lambda.NewFromConfig(cfg)
+}
+
+// NewRoute53Client creates the Route53 client
+func NewRoute53Client(cfg aws.Config) *route53.Client {
+	return route53.NewFromConfig(cfg)
+}
+
+// NewCloudControlClient creates the Cloud Control API client
+func NewCloudControlClient(cfg aws.Config) *cloudcontrol.Client {
+	return cloudcontrol.NewFromConfig(cfg)
+}
+
+// NewCloudFormationClient creates the CloudFormation client, only its registry of resource types is read
+func NewCloudFormationClient(cfg aws.Config) *cloudformation.Client {
+	return cloudformation.NewFromConfig(cfg)
+}
+
+// AccountID returns the id of the AWS account the credentials belong to
+func AccountID(ctx context.Context, cfg aws.Config) (string, error) {
+	output, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(output.Account), nil
+}
@@ -0,0 +1,117 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+// GetLaunchTemplates gets the launch templates from AWS account with the contents of their latest version, which is
+// the version terraform keeps in the state. The templates that no longer exist are left out
+func (a *AppAWSProvider) GetLaunchTemplates(ctx context.Context, templateIDs []string) (map[string]map[string]interface{}, error) {
+	templateMap := make(map[string]map[string]interface{})
+	if len(templateIDs) == 0 {
+		return templateMap, nil
+	}
+
+	templates, err := a.describeLaunchTemplates(ctx, templateIDs)
+	if isNotFound(err) {
+		// DescribeLaunchTemplates has no filter on ids and fails the whole call when one of them no longer exists, the
+		// templates are then described one at a time
+		templates = make([]types.LaunchTemplate, 0, len(templateIDs))
+		for _, id := range templateIDs {
+			template, err := a.describeLaunchTemplates(ctx, []string{id})
+			if isNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, describeError("launch templates", err)
+			}
+			templates = append(templates, template...)
+		}
+	} else if err != nil {
+		return nil, describeError("launch templates", err)
+	}
+
+	for _, template := range templates {
+		id := aws.ToString(template.LaunchTemplateId)
+		values, err := a.GetLaunchTemplateVersion(ctx, id, strconv.FormatInt(aws.ToInt64(template.LatestVersionNumber), 10))
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			values = make(map[string]interface{})
+		}
+		delete(values, "version_number")
+		values["name"] = aws.ToString(template.LaunchTemplateName)
+		values["default_version"] = aws.ToInt64(template.DefaultVersionNumber)
+		values["latest_version"] = aws.ToInt64(template.LatestVersionNumber)
+		values["tags"] = tagMap(template.Tags)
+		templateMap[id] = values
+	}
+	return templateMap, nil
+}
+
+// describeLaunchTemplates gets the launch templates of the ids
+func (a *AppAWSProvider) describeLaunchTemplates(ctx context.Context, templateIDs []string) ([]types.LaunchTemplate, error) {
+	templates := make([]types.LaunchTemplate, 0, len(templateIDs))
+	paginator := ec2.NewDescribeLaunchTemplatesPaginator(a.client, &ec2.DescribeLaunchTemplatesInput{LaunchTemplateIds: templateIDs})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, page.LaunchTemplates...)
+	}
+	return templates, nil
+}
+
+// GetLaunchTemplateVersion gets the contents of a launch template version, a version number or $Latest or $Default,
+// in their terraform shape. It returns nil when the template or the version does not exist
+func (a *AppAWSProvider) GetLaunchTemplateVersion(ctx context.Context, templateID, version string) (map[string]interface{}, error) {
+	result, err := a.client.DescribeLaunchTemplateVersions(ctx, &ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateId: aws.String(templateID),
+		Versions:         []string{version},
+	})
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, describeError("launch template versions", err)
+	}
+	if len(result.LaunchTemplateVersions) == 0 {
+		return nil, nil
+	}
+
+	templateVersion := result.LaunchTemplateVersions[0]
+	values := map[string]interface{}{
+		"version_number":         aws.ToInt64(templateVersion.VersionNumber),
+		"description":            aws.ToString(templateVersion.VersionDescription),
+		"image_id":               "",
+		"instance_type":          "",
+		"key_name":               "",
+		"user_data":              "",
+		"vpc_security_group_ids": []string{},
+	}
+	if data := templateVersion.LaunchTemplateData; data != nil {
+		values["image_id"] = aws.ToString(data.ImageId)
+		values["instance_type"] = string(data.InstanceType)
+		values["key_name"] = aws.ToString(data.KeyName)
+		values["user_data"] = aws.ToString(data.UserData)
+		values["vpc_security_group_ids"] = append([]string{}, data.SecurityGroupIds...)
+	}
+	return values, nil
+}
+
+// isNotFound reports whether err is the error EC2 answers a call with for an id that does not exist, e.g.
+// InvalidLaunchTemplateId.NotFound or InvalidLaunchTemplateId.VersionNotFound
+func isNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorCode(), "NotFound")
+}
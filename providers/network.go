@@ -0,0 +1,220 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/driftreport/entities"
+	"github.com/driftreport/utils"
+)
+
+// The VPC networking resources are returned in their terraform shape, keyed by resource id, so that they can be
+// compared attribute by attribute with the state. The resources that no longer exist are left out
+
+// GetVpcs gets the VPCs and their DNS attributes from AWS account
+func (a *AppAWSProvider) GetVpcs(ctx context.Context, vpcIDs []string) (map[string]map[string]interface{}, error) {
+	vpcMap := make(map[string]map[string]interface{})
+	for _, filters := range idFilters("vpc-id", vpcIDs) {
+		paginator := ec2.NewDescribeVpcsPaginator(a.client, &ec2.DescribeVpcsInput{Filters: filters})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, describeError("vpcs", err)
+			}
+			for _, vpc := range page.Vpcs {
+				id := aws.ToString(vpc.VpcId)
+				values := map[string]interface{}{
+					"cidr_block":       aws.ToString(vpc.CidrBlock),
+					"instance_tenancy": string(vpc.InstanceTenancy),
+					"dhcp_options_id":  aws.ToString(vpc.DhcpOptionsId),
+					"tags":             tagMap(vpc.Tags),
+				}
+				for _, association := range vpc.Ipv6CidrBlockAssociationSet {
+					values["ipv6_cidr_block"] = aws.ToString(association.Ipv6CidrBlock)
+				}
+
+				// the DNS settings are only returned by DescribeVpcAttribute, one attribute per call
+				for attribute, name := range map[types.VpcAttributeName]string{
+					types.VpcAttributeNameEnableDnsSupport:   "enable_dns_support",
+					types.VpcAttributeNameEnableDnsHostnames: "enable_dns_hostnames",
+				} {
+					result, err := a.client.DescribeVpcAttribute(ctx, &ec2.DescribeVpcAttributeInput{VpcId: vpc.VpcId, Attribute: attribute})
+					if err != nil {
+						return nil, describeError("vpc attribute", err)
+					}
+					if result.EnableDnsSupport != nil {
+						values[name] = aws.ToBool(result.EnableDnsSupport.Value)
+					}
+					if result.EnableDnsHostnames != nil {
+						values[name] = aws.ToBool(result.EnableDnsHostnames.Value)
+					}
+				}
+				vpcMap[id] = values
+			}
+		}
+	}
+	return vpcMap, nil
+}
+
+// GetSubnets gets the subnets from AWS account
+func (a *AppAWSProvider) GetSubnets(ctx context.Context, subnetIDs []string) (map[string]map[string]interface{}, error) {
+	subnetMap := make(map[string]map[string]interface{})
+	for _, filters := range idFilters("subnet-id", subnetIDs) {
+		paginator := ec2.NewDescribeSubnetsPaginator(a.client, &ec2.DescribeSubnetsInput{Filters: filters})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, describeError("subnets", err)
+			}
+			for _, subnet := range page.Subnets {
+				values := map[string]interface{}{
+					"vpc_id":                          aws.ToString(subnet.VpcId),
+					"cidr_block":                      aws.ToString(subnet.CidrBlock),
+					"availability_zone":               aws.ToString(subnet.AvailabilityZone),
+					"map_public_ip_on_launch":         aws.ToBool(subnet.MapPublicIpOnLaunch),
+					"assign_ipv6_address_on_creation": aws.ToBool(subnet.AssignIpv6AddressOnCreation),
+					"tags":                            tagMap(subnet.Tags),
+				}
+				for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+					values["ipv6_cidr_block"] = aws.ToString(association.Ipv6CidrBlock)
+				}
+				subnetMap[aws.ToString(subnet.SubnetId)] = values
+			}
+		}
+	}
+	return subnetMap, nil
+}
+
+// GetRouteTables gets the route tables and their routes from AWS account. The local route and the routes propagated
+// by a virtual private gateway are left out, like terraform does
+func (a *AppAWSProvider) GetRouteTables(ctx context.Context, routeTableIDs []string) (map[string]map[string]interface{}, error) {
+	routeTableMap := make(map[string]map[string]interface{})
+	for _, filters := range idFilters("route-table-id", routeTableIDs) {
+		paginator := ec2.NewDescribeRouteTablesPaginator(a.client, &ec2.DescribeRouteTablesInput{Filters: filters})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, describeError("route tables", err)
+			}
+			for _, routeTable := range page.RouteTables {
+				routes := make([]interface{}, 0, len(routeTable.Routes))
+				for _, route := range routeTable.Routes {
+					if aws.ToString(route.GatewayId) == "local" || route.Origin == types.RouteOriginEnableVgwRoutePropagation {
+						continue
+					}
+					// terraform keeps the gateway endpoints of a route apart from the gateways
+					gatewayID, endpointID := aws.ToString(route.GatewayId), ""
+					if strings.HasPrefix(gatewayID, "vpce-") {
+						gatewayID, endpointID = "", gatewayID
+					}
+					routes = append(routes, map[string]interface{}{
+						"cidr_block":                 aws.ToString(route.DestinationCidrBlock),
+						"ipv6_cidr_block":            aws.ToString(route.DestinationIpv6CidrBlock),
+						"destination_prefix_list_id": aws.ToString(route.DestinationPrefixListId),
+						"gateway_id":                 gatewayID,
+						"vpc_endpoint_id":            endpointID,
+						"nat_gateway_id":             aws.ToString(route.NatGatewayId),
+						"network_interface_id":       aws.ToString(route.NetworkInterfaceId),
+						"transit_gateway_id":         aws.ToString(route.TransitGatewayId),
+						"vpc_peering_connection_id":  aws.ToString(route.VpcPeeringConnectionId),
+						"egress_only_gateway_id":     aws.ToString(route.EgressOnlyInternetGatewayId),
+						"carrier_gateway_id":         aws.ToString(route.CarrierGatewayId),
+						"local_gateway_id":           aws.ToString(route.LocalGatewayId),
+						"core_network_arn":           aws.ToString(route.CoreNetworkArn),
+					})
+				}
+				propagatingVgws := make([]string, 0, len(routeTable.PropagatingVgws))
+				for _, vgw := range routeTable.PropagatingVgws {
+					propagatingVgws = append(propagatingVgws, aws.ToString(vgw.GatewayId))
+				}
+				routeTableMap[aws.ToString(routeTable.RouteTableId)] = map[string]interface{}{
+					"vpc_id":           aws.ToString(routeTable.VpcId),
+					"route":            routes,
+					"propagating_vgws": propagatingVgws,
+					"tags":             tagMap(routeTable.Tags),
+				}
+			}
+		}
+	}
+	return routeTableMap, nil
+}
+
+// GetInternetGateways gets the internet gateways and the VPC they are attached to from AWS account
+func (a *AppAWSProvider) GetInternetGateways(ctx context.Context, gatewayIDs []string) (map[string]map[string]interface{}, error) {
+	gatewayMap := make(map[string]map[string]interface{})
+	for _, filters := range idFilters("internet-gateway-id", gatewayIDs) {
+		paginator := ec2.NewDescribeInternetGatewaysPaginator(a.client, &ec2.DescribeInternetGatewaysInput{Filters: filters})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, describeError("internet gateways", err)
+			}
+			for _, gateway := range page.InternetGateways {
+				values := map[string]interface{}{
+					"vpc_id": "",
+					"tags":   tagMap(gateway.Tags),
+				}
+				for _, attachment := range gateway.Attachments {
+					values["vpc_id"] = aws.ToString(attachment.VpcId)
+				}
+				gatewayMap[aws.ToString(gateway.InternetGatewayId)] = values
+			}
+		}
+	}
+	return gatewayMap, nil
+}
+
+// GetNatGateways gets the NAT gateways from AWS account, deleted gateways are left out
+func (a *AppAWSProvider) GetNatGateways(ctx context.Context, gatewayIDs []string) (map[string]map[string]interface{}, error) {
+	gatewayMap := make(map[string]map[string]interface{})
+	for _, filters := range idFilters("nat-gateway-id", gatewayIDs) {
+		paginator := ec2.NewDescribeNatGatewaysPaginator(a.client, &ec2.DescribeNatGatewaysInput{Filter: filters})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, describeError("nat gateways", err)
+			}
+			for _, gateway := range page.NatGateways {
+				if gateway.State == types.NatGatewayStateDeleted || gateway.State == types.NatGatewayStateDeleting {
+					continue
+				}
+				values := map[string]interface{}{
+					"subnet_id":         aws.ToString(gateway.SubnetId),
+					"connectivity_type": string(gateway.ConnectivityType),
+					"tags":              tagMap(gateway.Tags),
+				}
+				for _, address := range gateway.NatGatewayAddresses {
+					if address.IsPrimary != nil && !aws.ToBool(address.IsPrimary) {
+						continue
+					}
+					values["allocation_id"] = aws.ToString(address.AllocationId)
+					values["private_ip"] = aws.ToString(address.PrivateIp)
+					values["public_ip"] = aws.ToString(address.PublicIp)
+				}
+				gatewayMap[aws.ToString(gateway.NatGatewayId)] = values
+			}
+		}
+	}
+	return gatewayMap, nil
+}
+
+// tagMap turns the AWS tag list into the terraform tags map
+func tagMap(tags []types.Tag) map[string]string {
+	tagsMap := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		tagsMap[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tagsMap
+}
+
+func describeError(resource string, err error) error {
+	utils.Logger.Sugar().Errorf("failed to describe %s: %v", resource, err)
+	return &entities.CustomError{
+		StatusCode: http.StatusBadRequest,
+		Err:        err,
+	}
+}
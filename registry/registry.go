@@ -0,0 +1,135 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/driftreport/entities"
+)
+
+type (
+	// Resource is a resource normalized from the terraform state or from AWS, so that both sides can be compared
+	Resource struct {
+		Type    string
+		Address string
+		ID      string
+		// Object is the typed form of the resource used by its handler, e.g. *entities.EC2Instance
+		Object interface{}
+		// Values are the attributes of the resource given to the custom rules
+		Values map[string]interface{}
+	}
+
+	// Handler adds the support of terraform resource types. Normalize decodes the resources of the state, Fetch gets
+	// the same resources from AWS and Compare returns the differences between both sides of a resource
+	Handler interface {
+		// Types are the terraform resource types of the handler, companion types, e.g. aws_security_group_rule, are
+		// normalized with the resource they belong to
+		Types() []string
+		Normalize(state State) ([]*Resource, error)
+		// Fetch returns the AWS side of the resources keyed by resource id, a resource missing in AWS is left out
+		Fetch(ctx context.Context, resources []*Resource) (map[string]*Resource, error)
+		// Compare is called with both sides set, a resource left out by Fetch is reported as removed without it
+		Compare(tfResource, awsResource *Resource, options *entities.ReportOptions) ([]*entities.Difference, error)
+	}
+
+	// UnmanagedLister is implemented by the handlers that also report the resources found in AWS but not in the
+	// state, e.g. the records of a hosted zone created by hand. Unmanaged picks them from the resources returned by
+	// Fetch, so that AWS is only read once
+	UnmanagedLister interface {
+		Unmanaged(tfResources []*Resource, awsResources map[string]*Resource) []*Resource
+	}
+
+	// Resolver creates the handlers of resource types without a registered handler once the types of the state are
+	// known, e.g. a generic handler for any type AWS describes. The types it cannot handle are left out
+	Resolver interface {
+		Resolve(ctx context.Context, resourceTypes []string) ([]Handler, error)
+	}
+
+	// Registry maps the terraform resource types to their handler
+	Registry struct {
+		handlers []Handler
+		byType   map[string]Handler
+		resolver Resolver
+	}
+)
+
+// New creates a registry with the handlers
+func New(handlers ...Handler) *Registry {
+	r := &Registry{byType: make(map[string]Handler)}
+	for _, handler := range handlers {
+		r.Register(handler)
+	}
+	return r
+}
+
+// Register adds a handler, a resource type can only have one handler
+func (r *Registry) Register(handler Handler) {
+	for _, resourceType := range handler.Types() {
+		if _, ok := r.byType[resourceType]; ok {
+			panic(fmt.Sprintf("registry: resource type %s is registered twice", resourceType))
+		}
+		r.byType[resourceType] = handler
+	}
+	r.handlers = append(r.handlers, handler)
+}
+
+// SetResolver sets the resolver of the resource types without a registered handler
+func (r *Registry) SetResolver(resolver Resolver) {
+	r.resolver = resolver
+}
+
+// Resolve registers the handlers the resolver creates for the resource types without a handler and returns them,
+// nothing is resolved without a resolver
+func (r *Registry) Resolve(ctx context.Context, resourceTypes []string) ([]Handler, error) {
+	if r.resolver == nil {
+		return nil, nil
+	}
+	unsupported := make([]string, 0, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		if _, ok := r.byType[resourceType]; !ok {
+			unsupported = append(unsupported, resourceType)
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil, nil
+	}
+	handlers, err := r.resolver.Resolve(ctx, unsupported)
+	if err != nil {
+		return nil, err
+	}
+	for _, handler := range handlers {
+		r.Register(handler)
+	}
+	return handlers, nil
+}
+
+// Handlers returns the handlers in the order they were registered
+func (r *Registry) Handlers() []Handler {
+	return r.handlers
+}
+
+// Lookup returns the handler of a resource type
+func (r *Registry) Lookup(resourceType string) (Handler, bool) {
+	handler, ok := r.byType[resourceType]
+	return handler, ok
+}
+
+// Types returns the supported resource types sorted by name
+func (r *Registry) Types() []string {
+	types := make([]string, 0, len(r.byType))
+	for resourceType := range r.byType {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// IDs returns the ids of the resources
+func IDs(resources []*Resource) []string {
+	ids := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		ids = append(ids, resource.ID)
+	}
+	return ids
+}
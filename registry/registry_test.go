@@ -0,0 +1,102 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// typesHandler only declares resource types
+type typesHandler struct {
+	types []string
+}
+
+func (h *typesHandler) Types() []string { return h.types }
+
+func (h *typesHandler) Normalize(state State) ([]*Resource, error) { return nil, nil }
+
+func (h *typesHandler) Fetch(ctx context.Context, resources []*Resource) (map[string]*Resource, error) {
+	return nil, nil
+}
+
+func (h *typesHandler) Compare(tfResource, awsResource *Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	return nil, nil
+}
+
+// typesResolver resolves the resource types it knows with a typesHandler
+type typesResolver struct {
+	known []string
+}
+
+func (r *typesResolver) Resolve(ctx context.Context, resourceTypes []string) ([]Handler, error) {
+	handlers := make([]Handler, 0)
+	for _, resourceType := range resourceTypes {
+		if slices.Contains(r.known, resourceType) {
+			handlers = append(handlers, &typesHandler{types: []string{resourceType}})
+		}
+	}
+	return handlers, nil
+}
+
+func TestRegistry(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	Convey("handlers are looked up by resource type", t, func() {
+		securityGroups := &typesHandler{types: []string{"aws_security_group", "aws_security_group_rule"}}
+		r := New(&typesHandler{types: []string{"aws_vpc"}}, securityGroups)
+
+		handler, ok := r.Lookup("aws_security_group_rule")
+		So(ok, ShouldBeTrue)
+		So(handler, ShouldEqual, securityGroups)
+		_, ok = r.Lookup("aws_s3_bucket")
+		So(ok, ShouldBeFalse)
+		So(r.Types(), ShouldResemble, []string{"aws_security_group", "aws_security_group_rule", "aws_vpc"})
+		So(len(r.Handlers()), ShouldEqual, 2)
+
+		So(func() { r.Register(&typesHandler{types: []string{"aws_vpc"}}) }, ShouldPanic)
+	})
+
+	Convey("the types without a handler are registered with the handlers of the resolver", t, func() {
+		r := New(&typesHandler{types: []string{"aws_vpc"}})
+		handlers, err := r.Resolve(context.Background(), []string{"aws_vpc", "aws_sqs_queue"})
+		So(err, ShouldBeNil)
+		So(handlers, ShouldBeEmpty)
+
+		r.SetResolver(&typesResolver{known: []string{"aws_vpc", "aws_sqs_queue"}})
+		handlers, err = r.Resolve(context.Background(), []string{"aws_vpc", "aws_sqs_queue", "random_id"})
+		So(err, ShouldBeNil)
+		So(handlers, ShouldHaveLength, 1)
+		So(handlers[0].Types(), ShouldResemble, []string{"aws_sqs_queue"})
+		So(r.Types(), ShouldResemble, []string{"aws_sqs_queue", "aws_vpc"})
+	})
+
+	Convey("resources of the state are grouped by type with their address", t, func() {
+		state, err := LoadState("../terraform.tfstate.json")
+		So(err, ShouldBeNil)
+		So(state.Has("aws_instance"), ShouldBeTrue)
+		So(state.Has("aws_vpc"), ShouldBeFalse)
+		So(resourceAddress("module.web", "aws_instance", "app", "blue"), ShouldEqual, `module.web.aws_instance.app["blue"]`)
+		So(resourceAddress("", "aws_instance", "app", float64(1)), ShouldEqual, "aws_instance.app[1]")
+	})
+
+	Convey("Test parsing an empty .tfstate file", t, func() {
+		var buffer bytes.Buffer
+		buffer.WriteString("")
+		content, err := io.ReadAll(&buffer)
+		So(err, ShouldBeNil)
+		err = os.WriteFile("../tfstate.json", content, 0644)
+		So(err, ShouldBeNil)
+
+		_, err = LoadState("../tfstate.json")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "failed with code 400: .tfstate is empty")
+	})
+}
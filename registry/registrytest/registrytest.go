@@ -0,0 +1,54 @@
+// Package registrytest checks the resources of a state with the handlers of a registry, for the tests of the handlers
+package registrytest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+)
+
+type (
+	// Results are the results of a check by resource address
+	Results map[string]*Result
+
+	// Result is the result of the check of a resource, Removed when Fetch left it out as it is gone from AWS, the
+	// differences of Compare otherwise
+	Result struct {
+		Removed     bool
+		Differences []*entities.Difference
+	}
+)
+
+// Check normalizes the resources of a type from the state, fetches them and compares them the way the drift report
+// does: a resource left out by Fetch is removed and not compared
+func Check(handlers *registry.Registry, resourceType string, state registry.State, options *entities.ReportOptions) (Results, error) {
+	handler, ok := handlers.Lookup(resourceType)
+	if !ok {
+		return nil, fmt.Errorf("no handler for %s", resourceType)
+	}
+	resources, err := handler.Normalize(state)
+	if err != nil {
+		return nil, err
+	}
+	awsResources, err := handler.Fetch(context.Background(), resources)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(Results, len(resources))
+	for _, resource := range resources {
+		awsResource, ok := awsResources[resource.ID]
+		if !ok || awsResource == nil {
+			results[resource.Address] = &Result{Removed: true}
+			continue
+		}
+		differences, err := handler.Compare(resource, awsResource, options)
+		if err != nil {
+			return nil, err
+		}
+		results[resource.Address] = &Result{Differences: differences}
+	}
+	return results, nil
+}
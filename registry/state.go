@@ -0,0 +1,69 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/utils"
+)
+
+type (
+	// StateResource is an instance of a managed resource of the terraform state
+	StateResource struct {
+		Address  string
+		Instance *entities.Instance
+	}
+
+	// State groups the resource instances of the terraform state by resource type, so that every type is decoded by
+	// its own handler
+	State map[string][]*StateResource
+)
+
+// LoadState reads the terraform.tfstate file once and groups its managed resources by type, data sources are not
+// deployed resources and are skipped
+func LoadState(filePath string) (State, error) {
+	state := make(State)
+	terraformState, err := utils.ParseTerraformState(filePath)
+	if err != nil {
+		utils.Logger.Sugar().Errorf("failed to parse terraform state file: %v", err)
+		return state, err
+	}
+
+	for _, resource := range terraformState.Resources {
+		if resource.Mode == "data" {
+			continue
+		}
+		for _, instance := range resource.Instances {
+			state[resource.Type] = append(state[resource.Type], &StateResource{
+				Address:  resourceAddress(resource.Module, resource.Type, resource.Name, instance.IndexKey),
+				Instance: instance,
+			})
+		}
+	}
+	return state, nil
+}
+
+// Has reports whether the state has resources of one of the types
+func (s State) Has(resourceTypes ...string) bool {
+	for _, resourceType := range resourceTypes {
+		if len(s[resourceType]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceAddress builds the terraform address of a resource instance, e.g. module.web.aws_instance.app["blue"]
+func resourceAddress(module, resourceType, name string, indexKey interface{}) string {
+	address := resourceType + "." + name
+	if module != "" {
+		address = module + "." + address
+	}
+	switch key := indexKey.(type) {
+	case string:
+		address += fmt.Sprintf("[%q]", key)
+	case float64:
+		address += fmt.Sprintf("[%d]", int(key))
+	}
+	return address
+}
@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/utils"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+)
+
+type (
+	// Engine evaluates the custom drift rules, written as CEL expressions over the variables terraform, aws
+	// (the attributes of the resource on each side), address and resource_type
+	Engine struct {
+		rules []*compiledRule
+	}
+
+	compiledRule struct {
+		rule    *entities.CustomRule
+		program cel.Program
+	}
+
+	// Result is the outcome of the rules of a resource
+	Result struct {
+		Findings []*entities.Finding
+		// Allowed are the attributes whose differences are accepted by a passing rule
+		Allowed []string
+	}
+)
+
+// NewEngine compiles the custom rules, so that an invalid expression fails before any resource is checked
+func NewEngine(customRules *entities.CustomRules) (*Engine, error) {
+	engine := &Engine{}
+	if customRules == nil {
+		return engine, nil
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("terraform", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("aws", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("address", cel.StringType),
+		cel.Variable("resource_type", cel.StringType),
+		ext.Strings(),
+	)
+	if err != nil {
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+		}
+	}
+
+	for _, rule := range customRules.Rules {
+		ast, issues := env.Compile(rule.Condition)
+		if issues != nil && issues.Err() != nil {
+			utils.Logger.Sugar().Errorf("invalid condition for rule %s: %v", rule.ID, issues.Err())
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        fmt.Errorf("rule %s: %w", rule.ID, issues.Err()),
+			}
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        fmt.Errorf("rule %s: condition must be a boolean expression", rule.ID),
+			}
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        fmt.Errorf("rule %s: %w", rule.ID, err),
+			}
+		}
+		engine.rules = append(engine.rules, &compiledRule{rule: rule, program: program})
+	}
+
+	return engine, nil
+}
+
+// Evaluate runs the rules matching the resource type against the terraform and aws attributes of a resource.
+// A condition that fails to evaluate, e.g. on a missing key, is reported as a finding as well
+func (e *Engine) Evaluate(resourceType, address string, terraform, aws map[string]interface{}) *Result {
+	result := &Result{}
+	if e == nil {
+		return result
+	}
+
+	variables := map[string]interface{}{
+		"terraform":     terraform,
+		"aws":           aws,
+		"address":       address,
+		"resource_type": resourceType,
+	}
+	for _, compiled := range e.rules {
+		rule := compiled.rule
+		if rule.ResourceType != "" && rule.ResourceType != resourceType {
+			continue
+		}
+
+		finding := &entities.Finding{
+			RuleID:      rule.ID,
+			Description: rule.Description,
+			Severity:    rule.Severity,
+		}
+		value, _, err := compiled.program.Eval(variables)
+		if err != nil {
+			utils.Logger.Sugar().Warnf("rule %s could not be evaluated for %s: %v", rule.ID, address, err)
+			finding.Description = fmt.Sprintf("%s (evaluation error: %v)", rule.Description, err)
+			result.Findings = append(result.Findings, finding)
+			continue
+		}
+
+		passed, ok := value.Value().(bool)
+		switch {
+		case ok && passed && rule.Attribute != "":
+			result.Allowed = append(result.Allowed, rule.Attribute)
+		case !ok || !passed:
+			result.Findings = append(result.Findings, finding)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,72 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRuleEngine(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	customRules := &entities.CustomRules{
+		Rules: []*entities.CustomRule{
+			{
+				ID:           "instance-family",
+				Description:  "instance_type may only change within the same family",
+				ResourceType: "aws_instance",
+				Attribute:    "instance_type",
+				Condition:    "terraform.instance_type.split('.')[0] == aws.instance_type.split('.')[0]",
+				Severity:     entities.SeverityHigh,
+			},
+			{
+				ID:          "owner-tag",
+				Description: "tag Owner must never be removed",
+				Condition:   "!('Owner' in terraform.tags) || 'Owner' in aws.tags",
+				Severity:    entities.SeverityCritical,
+			},
+		},
+	}
+	engine, err := NewEngine(customRules)
+
+	Convey("compile the rules", t, func() {
+		So(err, ShouldBeNil)
+		So(len(engine.rules), ShouldEqual, 2)
+	})
+
+	Convey("passing rules allow their attribute", t, func() {
+		result := engine.Evaluate("aws_instance", "aws_instance.web",
+			map[string]interface{}{"instance_type": "t3.micro", "tags": map[string]string{"Owner": "ops"}},
+			map[string]interface{}{"instance_type": "t3.large", "tags": map[string]string{"Owner": "dev"}})
+		So(result.Findings, ShouldBeEmpty)
+		So(result.Allowed, ShouldResemble, []string{"instance_type"})
+	})
+
+	Convey("failing rules produce findings", t, func() {
+		result := engine.Evaluate("aws_instance", "aws_instance.web",
+			map[string]interface{}{"instance_type": "t3.micro", "tags": map[string]string{"Owner": "ops"}},
+			map[string]interface{}{"instance_type": "m5.large", "tags": map[string]string{}})
+		So(result.Allowed, ShouldBeEmpty)
+		So(len(result.Findings), ShouldEqual, 2)
+		So(result.Findings[0].RuleID, ShouldEqual, "instance-family")
+		So(result.Findings[1].Severity, ShouldEqual, entities.SeverityCritical)
+	})
+
+	Convey("rules are filtered by resource type", t, func() {
+		result := engine.Evaluate("aws_launch_template", "aws_launch_template.web",
+			map[string]interface{}{"tags": map[string]string{}},
+			map[string]interface{}{"tags": map[string]string{}})
+		So(result.Findings, ShouldBeEmpty)
+		So(result.Allowed, ShouldBeEmpty)
+	})
+
+	Convey("invalid expressions are rejected", t, func() {
+		_, err := NewEngine(&entities.CustomRules{Rules: []*entities.CustomRule{{ID: "broken", Condition: "terraform.tags =="}}})
+		So(err, ShouldNotBeNil)
+		_, err = NewEngine(&entities.CustomRules{Rules: []*entities.CustomRule{{ID: "not-bool", Condition: "'a' + 'b'"}}})
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -5,45 +5,57 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
-	"sync"
 	"text/tabwriter"
+	"time"
 
 	"github.com/driftreport/entities"
-	"github.com/driftreport/providers"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/rules"
 	"github.com/driftreport/utils"
 )
 
+// stateFile is the terraform state the drift is checked for
+const stateFile = "../terraform.tfstate.json"
+
 type (
 	DriftReportService interface {
 		PrintDriftReport(ctx context.Context) error
 	}
 
 	AppDriftReportService struct {
-		awsProvider providers.AWSProvider
+		registry *registry.Registry
+		options  *entities.ReportOptions
+	}
+
+	// resourceCheck compares one resource with AWS and returns its drift report
+	resourceCheck struct {
+		address      string
+		resourceType string
+		id           string
+		run          func() (*entities.DriftReport, error)
 	}
 )
 
-func NewDriftReportService(awsProvider providers.AWSProvider) DriftReportService {
+func NewDriftReportService(handlers *registry.Registry, options *entities.ReportOptions) DriftReportService {
+	if options == nil {
+		options = &entities.ReportOptions{}
+	}
 	return &AppDriftReportService{
-		awsProvider: awsProvider,
+		registry: handlers,
+		options:  options,
 	}
 }
 
-// PrintDriftReport gets the instance map from Terraform state and AWS EC2 instance and parses both to drift checker
-// and prints (in JSON) the reports added on the buffered channel
+// PrintDriftReport loads the resources of the Terraform state and runs them through the pipeline: the handlers fetch
+// them from AWS, a pool of workers compares them and the reports are printed as they come
 func (s *AppDriftReportService) PrintDriftReport(ctx context.Context) error {
-	attributes := make(map[string]bool)
-	attributesList := "instance_type,security_groups,tags"
-	for _, attr := range strings.Split(attributesList, ",") {
-		attributes[attr] = true
-	}
-
-	// Load the terraform instance map and instances ids from  the terraform file
-	tfInstanceMap, instanceIds, err := loadTerraformStateInstances("../terraform.tfstate.json")
+	startTime := time.Now()
+	state, err := registry.LoadState(stateFile)
 	if err != nil {
 		utils.Logger.Sugar().Errorf("error loading Terraform state: %v", err)
 		return &entities.CustomError{
@@ -52,168 +64,323 @@ func (s *AppDriftReportService) PrintDriftReport(ctx context.Context) error {
 		}
 	}
 
-	// Check if any instances were found in the terraform state
-	if len(instanceIds) == 0 {
-		utils.Logger.Sugar().Error("Error: No tfInstances specified")
+	// Compile the custom rules before any resource is checked
+	ruleEngine, err := rules.NewEngine(s.options.CustomRules)
+	if err != nil {
+		utils.Logger.Sugar().Errorf("error compiling custom rules: %v", err)
+		return err
+	}
+
+	handlers := make([]registry.Handler, 0)
+	for _, handler := range s.registry.Handlers() {
+		if state.Has(handler.Types()...) {
+			handlers = append(handlers, handler)
+		}
+	}
+	// the types without a handler are checked with the handlers resolved for them, if any
+	resourceTypes := make([]string, 0, len(state))
+	for resourceType := range state {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	resolved, err := s.registry.Resolve(ctx, resourceTypes)
+	if err != nil {
+		utils.Logger.Sugar().Warnf("error resolving the resource types without a handler: %v", err)
+	}
+	handlers = append(handlers, resolved...)
+	for _, resourceType := range resourceTypes {
+		if _, ok := s.registry.Lookup(resourceType); !ok {
+			utils.Logger.Sugar().Warnf("resource type %s is not supported, its resources are not checked", resourceType)
+		}
+	}
+
+	// Check if any resources were found in the terraform state
+	if len(handlers) == 0 {
+		utils.Logger.Sugar().Error("Error: No supported resources in terraform state")
 		return &entities.CustomError{
 			StatusCode: http.StatusBadRequest,
-			Err:        errors.New("no instances specified"),
+			Err:        errors.New("no supported resources specified"),
 		}
 	}
 
-	// Get the AWS EC2 instance map with instance ids
-	awsEC2InstanceMap, err := s.awsProvider.GetEC2Instances(ctx, instanceIds)
-	if err != nil {
-		utils.Logger.Sugar().Errorf("error retrieving AWS config with err %v", err)
+	p := newPipeline(s.options.Workers, s.options.BatchSize)
+	exceeded, checkErrors := s.render(s.compare(ctx, p, s.fetch(ctx, p, handlers, state, ruleEngine)), startTime, os.Stdout)
+	if exceeded {
 		return &entities.CustomError{
-			StatusCode: http.StatusInternalServerError,
-			Err:        err,
+			StatusCode: http.StatusConflict,
+			Err:        errors.Join(append([]error{fmt.Errorf("drift at or above %s severity detected", s.options.FailOn)}, checkErrors...)...),
 		}
 	}
-
-	var wg sync.WaitGroup
-	reports := make(chan *entities.DriftReport, len(instanceIds))
-	for _, id := range instanceIds {
-		wg.Add(1)
-		go func(instanceID string) {
-			defer wg.Done()
-			var awsEC2Instance *entities.EC2Instance
-			var tfInstance *entities.EC2Instance
-			if _, ok := awsEC2InstanceMap[instanceID]; ok {
-				awsEC2Instance = awsEC2InstanceMap[instanceID]
-			}
-			if _, ok := tfInstanceMap[instanceID]; ok {
-				tfInstance = tfInstanceMap[instanceID]
-			}
-			select {
-			case <-ctx.Done():
-				log.Printf("drift check for instance %v failed with reason - %v", instanceID, ctx.Err())
-				return
-			default:
-				report, err := driftChecker(instanceID, awsEC2Instance, tfInstance, attributes)
-				if err != nil {
-					utils.Logger.Sugar().Errorf("error checking drift for instance %s: %v", instanceID, err)
-					return
-				}
-				reports <- report
-			}
-		}(id)
+	// the resources that could not be checked fail a strict run, otherwise the report is partial
+	if len(checkErrors) > 0 {
+		statusCode := http.StatusMultiStatus
+		if s.options.Strict {
+			statusCode = http.StatusFailedDependency
+		}
+		return &entities.CustomError{
+			StatusCode: statusCode,
+			Err:        fmt.Errorf("%d resources could not be checked: %w", len(checkErrors), errors.Join(checkErrors...)),
+		}
 	}
-	wg.Wait()
-	close(reports)
+	return nil
+}
 
-	var allReports []*entities.DriftReport
-	fmt.Println("\nPrint drift reports in JSON")
+// render prints the reports to w with the renderer of the output option. The reports are sorted by address unless another
+// order is requested, which needs the whole set of reports; a streamed renderer, e.g. the text output with -sort none
+// or the jsonl output, prints them as soon as they are compared. It returns whether a report exceeds the fail-on
+// severity and the errors of the error reports
+func (s *AppDriftReportService) render(reports <-chan *entities.DriftReport, startTime time.Time, w io.Writer) (bool, []error) {
+	exceeded := false
+	checkErrors := make([]error, 0)
+	counts := map[string]int{entities.ReportStatusOK: 0, entities.ReportStatusDrifted: 0, entities.ReportStatusError: 0}
+	r := newRenderer(s.options, w)
+	allReports := make([]*entities.DriftReport, 0)
+
+	r.open()
 	for report := range reports {
-		output, _ := json.MarshalIndent(report, "", "  ")
-		allReports = append(allReports, report)
-		fmt.Println(string(output))
+		if s.options.FailOn != "" && exceedsThreshold([]*entities.DriftReport{report}, s.options.FailOn) {
+			exceeded = true
+		}
+		if report.Status == entities.ReportStatusError {
+			checkErrors = append(checkErrors, fmt.Errorf("%s: %s", report.Address, report.Error))
+		}
+		counts[report.Status]++
+		if !r.streamed() {
+			allReports = append(allReports, report)
+			continue
+		}
+		sortDifferences(report, false)
+		r.render(report)
 	}
-	fmt.Println("\nPrint drift reports in tabular format")
-	printDriftTable(allReports)
-	return nil
+
+	sortReports(allReports, s.options.SortBy)
+	for _, report := range allReports {
+		r.render(report)
+	}
+	sort.SliceStable(checkErrors, func(i, j int) bool {
+		return checkErrors[i].Error() < checkErrors[j].Error()
+	})
+	r.close(s.metadata(counts, startTime, time.Now()))
+	return exceeded, checkErrors
 }
 
-// loadTerraformStateInstances reads and parses instances from the terraform.tfstate file
-func loadTerraformStateInstances(filePath string) (map[string]*entities.EC2Instance, []string, error) {
-	tfInstanceIds := make([]string, 0)
-	tfInstanceMap := make(map[string]*entities.EC2Instance)
-	terraformState, err := utils.ParseTerraformState(filePath)
+// resourceChecks fetches a batch of the resources normalized by the handler from AWS and returns a check per resource
+func (s *AppDriftReportService) resourceChecks(ctx context.Context, handler registry.Handler, tfResources []*registry.Resource, ruleEngine *rules.Engine) ([]resourceCheck, error) {
+	awsResources, err := handler.Fetch(ctx, tfResources)
 	if err != nil {
-		utils.Logger.Sugar().Errorf("failed to parse terraform state file: %v", err)
-		return tfInstanceMap, tfInstanceIds, err
+		return nil, err
 	}
 
-	// Filter out EC2 instances from the terraform state
-	tfInstances := make([]*entities.Instance, 0)
-	for _, resource := range terraformState.Resources {
-		if resource.Type == "aws_instance" {
-			tfInstances = append(tfInstances, resource.Instances...)
+	checks := make([]resourceCheck, 0, len(tfResources))
+	for _, resource := range tfResources {
+		tfResource := resource
+		checks = append(checks, resourceCheck{
+			address:      tfResource.Address,
+			resourceType: tfResource.Type,
+			id:           tfResource.ID,
+			run: func() (*entities.DriftReport, error) {
+				return checkResource(handler, tfResource, awsResources[tfResource.ID], s.options, ruleEngine)
+			},
+		})
+	}
+	if lister, ok := handler.(registry.UnmanagedLister); ok {
+		for _, resource := range lister.Unmanaged(tfResources, awsResources) {
+			awsResource := resource
+			checks = append(checks, resourceCheck{
+				address:      unmanagedAddress(awsResource),
+				resourceType: awsResource.Type,
+				id:           awsResource.ID,
+				run: func() (*entities.DriftReport, error) {
+					return unmanagedReport(awsResource, s.options), nil
+				},
+			})
 		}
 	}
+	return checks, nil
+}
 
-	// Check if any EC2 instances were found
-	if len(tfInstances) == 0 {
-		utils.Logger.Sugar().Error("no instances found in terraform state")
-		return tfInstanceMap, tfInstanceIds, &entities.CustomError{
-			StatusCode: http.StatusBadRequest,
-			Err:        errors.New("no instances found in terraform state"),
-		}
+// checkResource compares the terraform and AWS sides of a resource with its handler and creates a drift report.
+// Differences matched by the ignore rules or allowed by a custom rule are moved to the suppressed list of the report
+// instead of being dropped. A resource missing in AWS is reported as removed
+func checkResource(handler registry.Handler, tfResource, awsResource *registry.Resource, options *entities.ReportOptions, ruleEngine *rules.Engine) (*entities.DriftReport, error) {
+	if awsResource == nil {
+		return removedReport(tfResource, options), nil
+	}
+	differences, err := handler.Compare(tfResource, awsResource, options)
+	if err != nil {
+		return nil, err
 	}
 
-	// Iterate over the instances and populate the tfInstanceMap
-	for _, instance := range tfInstances {
-		tfInstanceIds = append(tfInstanceIds, instance.Attributes.InstanceID)
-		attrs := instance.Attributes
-		tfInstanceMap[instance.Attributes.InstanceID] = &entities.EC2Instance{
-			InstanceType:   attrs.Type,
-			SecurityGroups: attrs.SecurityGroups,
-			Tags:           attrs.Tags,
-		}
+	builder := newReportBuilder(tfResource.Type, tfResource.Address, tfResource.ID, options, ruleEngine)
+	builder.add(differences...)
+	return builder.build(tfResource.Values, awsResource.Values), nil
+}
+
+// unmanagedReport reports a resource found in AWS but not in the state as a single added "resource" difference. The
+// custom rules are not evaluated as there is no terraform side to compare with
+func unmanagedReport(awsResource *registry.Resource, options *entities.ReportOptions) *entities.DriftReport {
+	builder := newReportBuilder(awsResource.Type, unmanagedAddress(awsResource), awsResource.ID, options, nil)
+	builder.add(entities.NewDifference("resource", nil, awsResource.ID))
+	report := builder.build(nil, awsResource.Values)
+	report.Unmanaged = true
+	return report
+}
+
+// removedReport reports a resource of the state deleted from AWS as a single removed "resource" difference, the
+// mirror of an unmanaged resource. The custom rules are not evaluated as there is no AWS side to compare with
+func removedReport(tfResource *registry.Resource, options *entities.ReportOptions) *entities.DriftReport {
+	builder := newReportBuilder(tfResource.Type, tfResource.Address, tfResource.ID, options, nil)
+	builder.add(entities.NewDifference("resource", tfResource.ID, nil))
+	return builder.build(tfResource.Values, nil)
+}
+
+// unmanagedAddress is the address an unmanaged resource is reported and ignored with, e.g.
+// aws_route53_record.unmanaged["Z0123456789_test.example.com_A"]
+func unmanagedAddress(awsResource *registry.Resource) string {
+	return fmt.Sprintf("%s.unmanaged[%q]", awsResource.Type, awsResource.ID)
+}
+
+// metadata returns the metadata of the run, with the counts of reports by status completed by their total
+func (s *AppDriftReportService) metadata(counts map[string]int, startTime, endTime time.Time) *entities.ReportMetadata {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	counts["total"] = total
+	return &entities.ReportMetadata{
+		StartTime:    startTime.UTC(),
+		EndTime:      endTime.UTC(),
+		ToolVersion:  s.options.ToolVersion,
+		StateSources: []string{stateFile},
+		Region:       s.options.Region,
+		Account:      s.options.Account,
+		Counts:       counts,
 	}
+}
 
-	return tfInstanceMap, tfInstanceIds, nil
+// printDriftDocument prints the document of the json output
+func printDriftDocument(w io.Writer, document *entities.DriftDocument) {
+	output, _ := json.MarshalIndent(document, "", "  ")
+	fmt.Fprintln(w, string(output))
 }
 
-//DriftChecker compares instance from AWS EC2 and terraform tfstate json file and creates a drift report
-func driftChecker(instanceId string, ec2Instance, tfInstance *entities.EC2Instance, attributes map[string]bool) (*entities.DriftReport, error) {
-	if !attributes["instance_type"] && !attributes["security_groups"] && !attributes["tags"] {
-		log.Println("no attributes for instance")
-		return nil, &entities.CustomError{
-			StatusCode: http.StatusBadRequest,
-			Err:        errors.New("no attributes for instance"),
-		}
+// printDriftJSON prints a drift report in JSON
+func printDriftJSON(w io.Writer, report *entities.DriftReport) {
+	output, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Fprintln(w, string(output))
+}
+
+// printDriftTable prints drift report in a tabular format
+func printDriftTable(w io.Writer, reports []*entities.DriftReport) {
+	rows := make([]string, 0, len(reports))
+	for _, r := range reports {
+		rows = append(rows, driftRow(r))
 	}
+	printDriftRows(w, rows)
+}
 
-	if tfInstance == nil {
-		utils.Logger.Sugar().Errorf("error retrieving terraform config for instance %s", instanceId)
-		return nil, &entities.CustomError{
-			StatusCode: http.StatusBadRequest,
-			Err:        errors.New("terraform instance not set"),
-		}
+// printDriftRows prints the table rows of the reports under the table header
+func printDriftRows(w io.Writer, rows []string) {
+	writer := tabwriter.NewWriter(w, 0, 0, 3, ' ', tabwriter.Debug)
+	fmt.Fprintln(writer, "RESOURCE ID\tDRIFTED\tSEVERITY\tATTRIBUTES WITH DIFFERENCES")
+	for _, row := range rows {
+		fmt.Fprint(writer, row)
 	}
+	writer.Flush()
+}
 
-	if ec2Instance == nil {
-		utils.Logger.Sugar().Errorf("error retrieving AWS config for instance %s", instanceId)
-		return nil, &entities.CustomError{
-			StatusCode: http.StatusBadRequest,
-			Err:        errors.New("ec2 instance not set"),
+// driftRow formats the table row of a report, with a line per difference and finding of a drifted report
+func driftRow(r *entities.DriftReport) string {
+	if r.Status == entities.ReportStatusError {
+		return fmt.Sprintf("%s\t%t\t%s\t%s\n", r.ResourceID, r.Drifted, "-", "Error: "+r.Error)
+	}
+	if !r.Drifted {
+		return fmt.Sprintf("%s\t%t\t%s\t%s\n", r.ResourceID, r.Drifted, "-", "No differences")
+	}
+	detailLines := make([]string, 0, len(r.Differences)+len(r.Findings))
+	for _, difference := range r.Differences {
+		detail := fmt.Sprintf("%s (%s): %s", difference.Attribute, difference.Severity, difference)
+		if difference.Confidence != "" {
+			detail += fmt.Sprintf(" [%s]", difference.Confidence)
 		}
+		detailLines = append(detailLines, detail)
 	}
-
-	differences := make(map[string]string)
-	if attributes["instance_type"] && ec2Instance.InstanceType != tfInstance.InstanceType {
-		differences["instance_type"] = fmt.Sprintf("AWS: %s, Terraform: %s", ec2Instance.InstanceType, tfInstance.InstanceType)
+	for _, finding := range r.Findings {
+		detailLines = append(detailLines, fmt.Sprintf("rule %s (%s): %s", finding.RuleID, finding.Severity, finding.Description))
 	}
-	if attributes["security_groups"] && fmt.Sprintf("%v", ec2Instance.SecurityGroups) != fmt.Sprintf("%v", tfInstance.SecurityGroups) {
-		differences["security_groups"] = fmt.Sprintf("AWS: %v, Terraform: %v", ec2Instance.SecurityGroups, tfInstance.SecurityGroups)
+	return fmt.Sprintf("%s\t%t\t%s\t%s\n", r.ResourceID, r.Drifted, r.Severity, strings.Join(detailLines, ",\n "))
+}
+
+// sortReports orders the reports by address, by resource type then address, or from the most to the least severe
+// then by address. The differences of every report are sorted as well, so that the output is the same from run to
+// run
+func sortReports(reports []*entities.DriftReport, sortBy string) {
+	bySeverity := sortBy == entities.SortBySeverity
+	for _, report := range reports {
+		sortDifferences(report, bySeverity)
 	}
-	fmt.Println("tftags", tfInstance.Tags)
-	if attributes["tags"] && fmt.Sprintf("%v", ec2Instance.Tags) != fmt.Sprintf("%v", tfInstance.Tags) {
-		differences["tags"] = fmt.Sprintf("AWS: %v, Terraform: %v", ec2Instance.Tags, tfInstance.Tags)
+	sort.SliceStable(reports, func(i, j int) bool {
+		a, b := reports[i], reports[j]
+		switch {
+		case bySeverity && a.Severity != b.Severity:
+			return a.Severity.Rank() > b.Severity.Rank()
+		case sortBy == entities.SortByType && a.ResourceType != b.ResourceType:
+			return a.ResourceType < b.ResourceType
+		case a.Address != b.Address:
+			return a.Address < b.Address
+		}
+		return a.ResourceID < b.ResourceID
+	})
+}
+
+// sortDifferences orders the differences and the suppressed differences of a report by attribute path, from the most
+// to the least severe first when bySeverity is set, and the findings by rule id
+func sortDifferences(report *entities.DriftReport, bySeverity bool) {
+	for _, differences := range [][]*entities.Difference{report.Differences, report.Suppressed} {
+		sort.SliceStable(differences, func(i, j int) bool {
+			a, b := differences[i], differences[j]
+			if bySeverity && a.Severity != b.Severity {
+				return a.Severity.Rank() > b.Severity.Rank()
+			}
+			return a.Attribute < b.Attribute
+		})
 	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].RuleID < report.Findings[j].RuleID
+	})
+}
 
-	return &entities.DriftReport{
-		InstanceID:  instanceId,
-		Drifted:     len(differences) > 0,
-		Differences: differences,
-	}, nil
+type severityGroup struct {
+	label   string
+	reports []*entities.DriftReport
 }
 
-//printDriftTable prints drift report in a tabular format
-func printDriftTable(reports []*entities.DriftReport) {
-	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.Debug)
-	fmt.Fprintln(writer, "INSTANCE ID\tDRIFTED\tATTRIBUTES WITH DIFFERENCES")
-	for _, r := range reports {
-		if r.Drifted {
-			detailLines := make([]string, 0, len(r.Differences))
-			for attr, detail := range r.Differences {
-				detailLines = append(detailLines, fmt.Sprintf("%s: %s", attr, detail))
+// groupBySeverity splits the reports by severity from critical to info, reports without drift come last
+func groupBySeverity(reports []*entities.DriftReport) []*severityGroup {
+	groups := make([]*severityGroup, 0)
+	severities := []entities.Severity{entities.SeverityCritical, entities.SeverityHigh, entities.SeverityMedium, entities.SeverityLow, entities.SeverityInfo, ""}
+	for _, severity := range severities {
+		group := &severityGroup{label: string(severity)}
+		if severity == "" {
+			group.label = "none"
+		}
+		for _, report := range reports {
+			if report.Severity == severity {
+				group.reports = append(group.reports, report)
 			}
-			fmt.Fprintf(writer, "%s\t%t\t%s\n", r.InstanceID, r.Drifted, strings.Join(detailLines, ",\n "))
-		} else {
-			fmt.Fprintf(writer, "%s\t%t\t%s\n", r.InstanceID, r.Drifted, "No differences")
+		}
+		if len(group.reports) > 0 {
+			groups = append(groups, group)
 		}
 	}
-	writer.Flush()
+	return groups
+}
+
+// exceedsThreshold reports whether a drifted report has a difference at or above the threshold severity
+func exceedsThreshold(reports []*entities.DriftReport, threshold entities.Severity) bool {
+	for _, report := range reports {
+		if report.Drifted && report.Severity.Rank() >= threshold.Rank() {
+			return true
+		}
+	}
+	return false
 }
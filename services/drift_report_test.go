@@ -3,21 +3,37 @@ package services
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/driftreport/entities"
+	"github.com/driftreport/handlers"
+	"github.com/driftreport/handlers/instance"
 	"github.com/driftreport/mocks"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/rules"
 	"github.com/driftreport/utils"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 func TestDriftReportService(t *testing.T) {
 	awsProvider := mocks.NewAWSProvider()
-	driftSvc := NewDriftReportService(awsProvider)
+	driftSvc := NewDriftReportService(handlers.NewRegistry(handlers.Clients{AWSProvider: awsProvider}), nil)
+	handler := instance.NewHandler(awsProvider)
 	logger := utils.InitZapLog()
 	defer logger.Sync() // Flush any buffered log messages
 
@@ -26,100 +42,736 @@ func TestDriftReportService(t *testing.T) {
 	ctx1, cancel1 := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel1()
 
-	Convey("load terraform instances from terraform.tfstate.json file", t, func() {
-		_, instanceIds, err := loadTerraformStateInstances("../terraform.tfstate.json")
-		So(err, ShouldBeNil)
-		So(len(instanceIds), ShouldEqual, 1)
-	})
-
-	Convey("Test parsing an empty .tfstate file", t, func() {
-		var buffer bytes.Buffer
-		buffer.WriteString("")
-		content, err := io.ReadAll(&buffer)
-		So(err, ShouldBeNil)
-		err = os.WriteFile("../tfstate.json", content, 0644)
-		So(err, ShouldBeNil)
-
-		_, _, err = loadTerraformStateInstances("../tfstate.json")
-		So(err, ShouldNotBeNil)
-		So(err.Error(), ShouldEqual, "failed with code 400: .tfstate is empty")
-	})
-
 	Convey("test print drift report tabular format if drifted", t, func() {
-		rescueStdout := os.Stdout
-		r, w, _ := os.Pipe()
-		os.Stdout = w
+		var out bytes.Buffer
 		driftReports := []*entities.DriftReport{
 			{
 				InstanceID: "rhhejbdjenfr",
+				ResourceID: "rhhejbdjenfr",
 				Drifted: true,
-				Differences: map[string]string{
-					"security_groups":"AWS: [sg-091fde8327f3fe99a], Terraform: [example-security-group]",
+				Severity: entities.SeverityHigh,
+				Differences: []*entities.Difference{
+					{Attribute: "security_groups.sg-091fde8327f3fe99a", Kind: "added", Expected: "<missing>", Actual: "sg-091fde8327f3fe99a", Severity: entities.SeverityHigh},
 				},
 			},
 		}
-		printDriftTable(driftReports)
-		w.Close()
-		out, _ := io.ReadAll(r)
-		os.Stdout = rescueStdout
-		So(string(out), ShouldEqual, "INSTANCE ID    |DRIFTED   |ATTRIBUTES WITH DIFFERENCES\nrhhejbdjenfr   |true      |security_groups: AWS: [sg-091fde8327f3fe99a], Terraform: [example-security-group]\n")
+		printDriftTable(&out, driftReports)
+		So(out.String(), ShouldEqual, "RESOURCE ID    |DRIFTED   |SEVERITY   |ATTRIBUTES WITH DIFFERENCES\nrhhejbdjenfr   |true      |high       |security_groups.sg-091fde8327f3fe99a (high): AWS: sg-091fde8327f3fe99a, Terraform: <missing>\n")
 	})
 
 	Convey("test print drift report tabular format if not drifted", t, func() {
-		rescueStdout := os.Stdout
-		r, w, _ := os.Pipe()
-		os.Stdout = w
+		var out bytes.Buffer
 		driftReports := []*entities.DriftReport{
 			{
 				InstanceID: "rhhejbdjenfr",
+				ResourceID: "rhhejbdjenfr",
 				Drifted: false,
-				Differences: map[string]string{
-					"security_groups":"AWS: [sg-091fde8327f3fe99a], Terraform: [sg-091fde8327f3fe99a]",
-				},
+				Differences: []*entities.Difference{},
 			},
 		}
-		printDriftTable(driftReports)
-		w.Close()
-		out, _ := io.ReadAll(r)
-		os.Stdout = rescueStdout
-		So(string(out), ShouldEqual, "INSTANCE ID    |DRIFTED   |ATTRIBUTES WITH DIFFERENCES\nrhhejbdjenfr   |false     |No differences\n")
+		printDriftTable(&out, driftReports)
+		So(out.String(), ShouldEqual, "RESOURCE ID    |DRIFTED   |SEVERITY   |ATTRIBUTES WITH DIFFERENCES\nrhhejbdjenfr   |false     |-          |No differences\n")
 	})
 
-	Convey("general drift report with no attributes", t, func() {
-		tfInstanceMap, instanceIds, err := loadTerraformStateInstances("../terraform.tfstate.json")
+	Convey("ignored differences are reported as suppressed", t, func() {
+		options := &entities.ReportOptions{
+			IgnoreRules: &entities.IgnoreRules{
+				Rules: []*entities.IgnoreRule{{Address: "aws_instance.*", Attributes: []string{"tags.aws:*"}}},
+			},
+			IncludeReservedTags: true,
+		}
+		ec2Instance := &entities.EC2Instance{
+			InstanceType: "t2.micro",
+			Tags:         map[string]string{"Name": "web", "aws:backup:source-resource": "i-1"},
+		}
+		tfInstance := &entities.EC2Instance{
+			Address:      "aws_instance.web",
+			InstanceType: "t2.micro",
+			Tags:         map[string]string{"Name": "web"},
+		}
+		report, err := checkResource(handler, instance.NewResource("i-1", tfInstance), instance.NewResource("i-1", ec2Instance), options, nil)
+		So(err, ShouldBeNil)
+		So(report.Drifted, ShouldBeFalse)
+		So(report.InstanceID, ShouldEqual, "i-1")
+		So(report.Address, ShouldEqual, "aws_instance.web")
+		So(differenceDetails(report.Suppressed), ShouldContainKey, "tags.aws:backup:source-resource")
+
+		tfInstance.Tags = map[string]string{"Name": "api"}
+		report, err = checkResource(handler, instance.NewResource("i-1", tfInstance), instance.NewResource("i-1", ec2Instance), options, nil)
 		So(err, ShouldBeNil)
-		So(len(instanceIds), ShouldEqual, 1)
+		So(report.Drifted, ShouldBeTrue)
+		So(differenceDetails(report.Differences)["tags.Name"], ShouldEqual, "AWS: web, Terraform: api")
+	})
 
-		attributesList := "instance_type,security_groups,tags"
-		attributes := make(map[string]bool)
-		for _, attr := range strings.Split(attributesList, ",") {
-			attributes[attr] = false
+	Convey("differences are classified by the drift policy", t, func() {
+		options := &entities.ReportOptions{Policy: entities.DefaultDriftPolicy()}
+		tfInstance := &entities.EC2Instance{
+			Address:        "aws_instance.web",
+			InstanceType:   "t2.micro",
+			SecurityGroups: []string{"sg-1"},
+			Tags:           map[string]string{"Name": "web"},
+			Blocks: map[string]interface{}{
+				"metadata_options": []interface{}{map[string]interface{}{"http_tokens": "required"}},
+			},
+		}
+		ec2Instance := &entities.EC2Instance{
+			InstanceType:   "t2.large",
+			SecurityGroups: []string{"sg-1", "sg-2"},
+			Tags:           map[string]string{"Name": "api"},
+			Blocks: map[string]interface{}{
+				"metadata_options": []interface{}{map[string]interface{}{"http_tokens": "optional"}},
+			},
+		}
+		report, err := checkResource(handler, instance.NewResource("i-1", tfInstance), instance.NewResource("i-1", ec2Instance), options, nil)
+		So(err, ShouldBeNil)
+		severities := make(map[string]entities.Severity)
+		for _, difference := range report.Differences {
+			severities[difference.Attribute] = difference.Severity
 		}
-		_, err = driftChecker(instanceIds[0], nil, tfInstanceMap[instanceIds[0]], attributes)
-		So(err, ShouldNotBeNil)
+		So(severities, ShouldResemble, map[string]entities.Severity{
+			"instance_type":                   entities.SeverityMedium,
+			"security_groups.sg-2":            entities.SeverityHigh,
+			"tags.Name":                       entities.SeverityLow,
+			"metadata_options[0].http_tokens": entities.SeverityCritical,
+		})
+		So(report.Severity, ShouldEqual, entities.SeverityCritical)
+
+		reports := []*entities.DriftReport{{Drifted: true, Severity: entities.SeverityLow}, report}
+		sortReports(reports, entities.SortBySeverity)
+		So(reports[0], ShouldEqual, report)
+		So(report.Differences[0].Severity, ShouldEqual, entities.SeverityCritical)
+		So(exceedsThreshold(reports, entities.SeverityHigh), ShouldBeTrue)
+		So(exceedsThreshold(reports[1:], entities.SeverityMedium), ShouldBeFalse)
+
+		groups := groupBySeverity(append(reports, &entities.DriftReport{}))
+		So(len(groups), ShouldEqual, 3)
+		So(groups[2].label, ShouldEqual, "none")
 	})
 
-	Convey("general drift report with attributes", t, func() {
-		tfInstanceMap, instanceIds, err := loadTerraformStateInstances("../terraform.tfstate.json")
+	Convey("custom rules allow differences and add findings", t, func() {
+		ruleEngine, err := rules.NewEngine(&entities.CustomRules{
+			Rules: []*entities.CustomRule{
+				{
+					ID:        "instance-family",
+					Attribute: "instance_type",
+					Condition: "terraform.instance_type.split('.')[0] == aws.instance_type.split('.')[0]",
+					Severity:  entities.SeverityHigh,
+				},
+			},
+		})
 		So(err, ShouldBeNil)
-		So(len(instanceIds), ShouldEqual, 1)
+		tfInstance := instance.NewResource("i-1", &entities.EC2Instance{Address: "aws_instance.web", InstanceType: "t3.micro"})
+
+		report, err := checkResource(handler, tfInstance, instance.NewResource("i-1", &entities.EC2Instance{InstanceType: "t3.large"}), &entities.ReportOptions{}, ruleEngine)
+		So(err, ShouldBeNil)
+		So(report.Drifted, ShouldBeFalse)
+		So(differenceDetails(report.Suppressed), ShouldContainKey, "instance_type")
+
+		report, err = checkResource(handler, tfInstance, instance.NewResource("i-1", &entities.EC2Instance{InstanceType: "m5.large"}), &entities.ReportOptions{}, ruleEngine)
+		So(err, ShouldBeNil)
+		So(report.Drifted, ShouldBeTrue)
+		So(report.Findings[0].RuleID, ShouldEqual, "instance-family")
+		So(report.Severity, ShouldEqual, entities.SeverityHigh)
+	})
+
+	Convey("unmanaged resources are reported as added and can be ignored by address", t, func() {
+		record := &registry.Resource{Type: "aws_route53_record", ID: "Z0123456789_legacy.example.com_CNAME"}
+		report := unmanagedReport(record, &entities.ReportOptions{})
+		So(report.Unmanaged, ShouldBeTrue)
+		So(report.Drifted, ShouldBeTrue)
+		So(report.Address, ShouldEqual, `aws_route53_record.unmanaged["Z0123456789_legacy.example.com_CNAME"]`)
+		So(report.Differences[0].Kind, ShouldEqual, entities.ChangeKindAdded)
+
+		report = unmanagedReport(record, &entities.ReportOptions{
+			IgnoreRules: &entities.IgnoreRules{
+				Rules: []*entities.IgnoreRule{{Address: "aws_route53_record.unmanaged", Attributes: []string{"*"}}},
+			},
+		})
+		So(report.Drifted, ShouldBeFalse)
+		So(report.Suppressed, ShouldHaveLength, 1)
+	})
+
+	Convey("a resource deleted from AWS is reported as a critical removed resource", t, func() {
+		// the mocked EC2 API has no instances and fails a call by instance id with InvalidInstanceID.NotFound, like EC2
+		svc := &AppDriftReportService{options: &entities.ReportOptions{Policy: entities.DefaultDriftPolicy()}}
+		state, err := registry.LoadState(stateFile)
+		So(err, ShouldBeNil)
+		resources, err := handler.Normalize(state)
+		So(err, ShouldBeNil)
+		checks, err := svc.resourceChecks(ctx, handler, resources, nil)
+		So(err, ShouldBeNil)
+		So(checks, ShouldHaveLength, 1)
+
+		report, err := checks[0].run()
+		So(err, ShouldBeNil)
+		So(report.Status, ShouldEqual, entities.ReportStatusDrifted)
+		So(report.Severity, ShouldEqual, entities.SeverityCritical)
+		So(report.Differences, ShouldHaveLength, 1)
+		So(report.Differences[0].Attribute, ShouldEqual, "resource")
+		So(report.Differences[0].Kind, ShouldEqual, entities.ChangeKindRemoved)
+		So(report.Differences[0].Expected, ShouldEqual, checks[0].id)
+		So(report.Differences[0].Actual, ShouldEqual, "<missing>")
+	})
 
-		attributesList := "instance_type,security_groups,tags"
-		attributes := make(map[string]bool)
-		for _, attr := range strings.Split(attributesList, ",") {
-			attributes[attr] = true
+	Convey("the resources of a handler are fetched in bounded batches by the pool of workers", t, func() {
+		svc := &AppDriftReportService{options: &entities.ReportOptions{}}
+		state := registry.State{}
+		for i := 0; i < 25; i++ {
+			state["aws_instance"] = append(state["aws_instance"], &registry.StateResource{
+				Address:  fmt.Sprintf("aws_instance.web[%d]", i),
+				Instance: &entities.Instance{RawAttributes: json.RawMessage(fmt.Sprintf(`{"id": "i-%d"}`, i))},
+			})
 		}
-		_, err = driftChecker(instanceIds[0], nil, tfInstanceMap[instanceIds[0]], attributes)
-		So(err, ShouldNotBeNil)
-		So(err.Error(), ShouldEqual, "failed with code 400: ec2 instance not set")
+		batched := &batchHandler{failing: "i-12"}
 
-		_, err = driftChecker(instanceIds[0], &entities.EC2Instance{}, nil, attributes)
-		So(err, ShouldNotBeNil)
-		So(err.Error(), ShouldEqual, "failed with code 400: terraform instance not set")
+		reports := make(map[string]*entities.DriftReport)
+		for report := range svc.compare(ctx, newPipeline(4, 10), svc.fetch(ctx, newPipeline(4, 10), []registry.Handler{batched}, state, nil)) {
+			reports[report.ResourceID] = report
+		}
+		So(reports, ShouldHaveLength, 25)
+		So(batched.batchSizes(), ShouldResemble, []int{5, 10, 10})
+		// only the resources of the failing batch are errors
+		So(reports["i-5"].Status, ShouldEqual, entities.ReportStatusOK)
+		So(reports["i-12"].Status, ShouldEqual, entities.ReportStatusError)
+		So(reports["i-19"].Status, ShouldEqual, entities.ReportStatusError)
+		So(reports["i-20"].Status, ShouldEqual, entities.ReportStatusOK)
+	})
+
+	Convey("the pipeline compares the checks with a bounded pool of workers", t, func() {
+		svc := &AppDriftReportService{options: &entities.ReportOptions{}}
+		p := newPipeline(2, 0)
+
+		checks := make(chan resourceCheck)
+		var running, maxRunning int32
+		go func() {
+			defer close(checks)
+			for i := 0; i < 50; i++ {
+				id := fmt.Sprintf("i-%d", i)
+				checks <- resourceCheck{address: "aws_instance.web[\"" + id + "\"]", resourceType: "aws_instance", id: id, run: func() (*entities.DriftReport, error) {
+					current := atomic.AddInt32(&running, 1)
+					defer atomic.AddInt32(&running, -1)
+					for {
+						seen := atomic.LoadInt32(&maxRunning)
+						if current <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, current) {
+							break
+						}
+					}
+					time.Sleep(time.Millisecond)
+					if id == "i-7" {
+						return nil, errors.New("describe failed")
+					}
+					return &entities.DriftReport{ResourceID: id, Status: entities.ReportStatusOK}, nil
+				}}
+			}
+		}()
+
+		failed := make([]*entities.DriftReport, 0)
+		count := 0
+		for report := range svc.compare(context.Background(), p, checks) {
+			count++
+			if report.Status == entities.ReportStatusError {
+				failed = append(failed, report)
+			}
+		}
+		So(count, ShouldEqual, 50)
+		So(atomic.LoadInt32(&maxRunning), ShouldBeLessThanOrEqualTo, 2)
+		So(failed, ShouldHaveLength, 1)
+		So(failed[0].ResourceID, ShouldEqual, "i-7")
+		So(failed[0].Error, ShouldEqual, "describe failed")
+	})
+
+	Convey("the checks left when the context is done are reported as errors", t, func() {
+		svc := &AppDriftReportService{options: &entities.ReportOptions{}}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		checks := make(chan resourceCheck, 1)
+		checks <- resourceCheck{address: "aws_instance.web", resourceType: "aws_instance", run: func() (*entities.DriftReport, error) {
+			return &entities.DriftReport{}, nil
+		}}
+		close(checks)
+
+		report := <-svc.compare(ctx, newPipeline(1, 0), checks)
+		So(report.Status, ShouldEqual, entities.ReportStatusError)
+		So(report.ResourceID, ShouldEqual, "aws_instance.web")
+		So(report.Error, ShouldEqual, context.Canceled.Error())
+	})
+
+	Convey("error reports are aggregated, and fail a strict run", t, func() {
+		svc := &AppDriftReportService{options: &entities.ReportOptions{}}
+		reports := func() <-chan *entities.DriftReport {
+			reports := make(chan *entities.DriftReport, 2)
+			reports <- &entities.DriftReport{Address: "aws_instance.web", Status: entities.ReportStatusError, Error: "throttled"}
+			reports <- &entities.DriftReport{Address: "aws_instance.api", Status: entities.ReportStatusOK}
+			close(reports)
+			return reports
+		}
+
+		exceeded, checkErrors := svc.render(reports(), time.Now(), io.Discard)
+		So(exceeded, ShouldBeFalse)
+		So(checkErrors, ShouldHaveLength, 1)
+		So(errors.Join(checkErrors...).Error(), ShouldEqual, "aws_instance.web: throttled")
+		So(driftRow(<-reports()), ShouldEqual, "\tfalse\t-\tError: throttled\n")
 	})
 
 	Convey("print drift report within context deadline ", t, func() {
+		// the mocked EC2 API does not have the instance of the state, it is reported as removed
 		err := driftSvc.PrintDriftReport(ctx1)
 		So(err, ShouldBeNil)
+
+		strictSvc := NewDriftReportService(handlers.NewRegistry(handlers.Clients{AWSProvider: awsProvider}), &entities.ReportOptions{Strict: true})
+		So(strictSvc.PrintDriftReport(ctx1), ShouldBeNil)
+	})
+
+	Convey("a run whose resources could not be checked is partial, and fails when strict", t, func() {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		err := driftSvc.PrintDriftReport(cancelled)
+		var customErr *entities.CustomError
+		So(errors.As(err, &customErr), ShouldBeTrue)
+		So(customErr.StatusCode, ShouldEqual, http.StatusMultiStatus)
+
+		strictSvc := NewDriftReportService(handlers.NewRegistry(handlers.Clients{AWSProvider: awsProvider}), &entities.ReportOptions{Strict: true})
+		err = strictSvc.PrintDriftReport(cancelled)
+		So(errors.As(err, &customErr), ShouldBeTrue)
+		So(customErr.StatusCode, ShouldEqual, http.StatusFailedDependency)
+	})
+}
+
+var update = flag.Bool("update", false, "update the golden files of the rendered reports")
+
+func TestRenderGolden(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	for _, golden := range []struct {
+		name    string
+		options *entities.ReportOptions
+	}{
+		{name: "sort_address", options: &entities.ReportOptions{SortBy: entities.SortByAddress}},
+		{name: "sort_severity", options: &entities.ReportOptions{SortBy: entities.SortBySeverity}},
+		{name: "sort_type", options: &entities.ReportOptions{SortBy: entities.SortByType}},
+		{name: "group_by_severity", options: &entities.ReportOptions{SortBy: entities.SortByAddress, GroupBy: entities.GroupBySeverity}},
+	} {
+		Convey("the "+golden.name+" output is the same whatever order the reports are compared in", t, func() {
+			path := filepath.Join("..", "testdata", "golden", "report_"+golden.name+".golden")
+			output := render(golden.options, goldenReports(0, 1, 2, 3))
+			if *update {
+				So(os.WriteFile(path, []byte(output), 0o644), ShouldBeNil)
+			}
+			expected, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(output, ShouldEqual, string(expected))
+			So(render(golden.options, goldenReports(3, 2, 1, 0)), ShouldEqual, string(expected))
+			So(render(golden.options, goldenReports(1, 3, 0, 2)), ShouldEqual, string(expected))
+		})
+	}
+}
+
+func TestDriftDocumentSchema(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	schema, err := jsonschema.Compile(filepath.Join("..", "schema", "drift-report.schema.json"))
+	if err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+	options := &entities.ReportOptions{Output: entities.OutputJSON, ToolVersion: "v1.2.3", Region: "us-east-1", Account: "123456789012"}
+
+	Convey("the json output is a single document valid against the schema", t, func() {
+		output := render(options, goldenReports(1, 3, 0, 2))
+		decoder := json.NewDecoder(strings.NewReader(output))
+		var document interface{}
+		So(decoder.Decode(&document), ShouldBeNil)
+		So(decoder.Decode(&document), ShouldEqual, io.EOF)
+		So(json.Unmarshal([]byte(output), &document), ShouldBeNil)
+		So(schema.Validate(document), ShouldBeNil)
+
+		var driftDocument entities.DriftDocument
+		So(json.Unmarshal([]byte(output), &driftDocument), ShouldBeNil)
+		So(driftDocument.Metadata.ToolVersion, ShouldEqual, "v1.2.3")
+		So(driftDocument.Metadata.StateSources, ShouldResemble, []string{stateFile})
+		So(driftDocument.Metadata.EndTime.Before(driftDocument.Metadata.StartTime), ShouldBeFalse)
+		So(driftDocument.Metadata.Counts, ShouldResemble, map[string]int{"ok": 1, "drifted": 2, "error": 1, "total": 4})
+		So(driftDocument.Reports, ShouldHaveLength, 4)
+		So(driftDocument.Reports[0].Address, ShouldEqual, "aws_iam_role.deploy")
+	})
+
+	Convey("the json document matches its golden file", t, func() {
+		svc := &AppDriftReportService{options: options}
+		reports := make([]*entities.DriftReport, 0)
+		counts := map[string]int{}
+		for report := range goldenReports(0, 1, 2, 3) {
+			reports = append(reports, report)
+			counts[report.Status]++
+		}
+		sortReports(reports, entities.SortByAddress)
+		startTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		var buffer bytes.Buffer
+		r := newRenderer(options, &buffer)
+		r.open()
+		for _, report := range reports {
+			r.render(report)
+		}
+		r.close(svc.metadata(counts, startTime, startTime.Add(3*time.Second)))
+
+		path := filepath.Join("..", "testdata", "golden", "report_json.golden")
+		if *update {
+			So(os.WriteFile(path, buffer.Bytes(), 0o644), ShouldBeNil)
+		}
+		expected, err := os.ReadFile(path)
+		So(err, ShouldBeNil)
+		So(buffer.String(), ShouldEqual, string(expected))
+
+		var document interface{}
+		So(json.Unmarshal(expected, &document), ShouldBeNil)
+		So(schema.Validate(document), ShouldBeNil)
+	})
+
+	Convey("documents that do not follow the schema are rejected", t, func() {
+		for _, invalid := range []string{
+			`{"reports": []}`,
+			`{"metadata": {"start_time": "2024-05-01T12:00:00Z", "end_time": "2024-05-01T12:00:03Z", "tool_version": "dev", "state_sources": [], "counts": {"ok": 0, "drifted": 0, "error": 0, "total": 0}}, "reports": [{"resource_id": "i-0aaa", "drifted": true, "status": "unknown", "differences": []}]}`,
+		} {
+			var document interface{}
+			So(json.Unmarshal([]byte(invalid), &document), ShouldBeNil)
+			So(schema.Validate(document), ShouldNotBeNil)
+		}
+	})
+}
+
+func TestJSONLinesOutput(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	Convey("the jsonl output is a line per report in the order they are compared, then a summary line", t, func() {
+		output := render(&entities.ReportOptions{Output: entities.OutputJSONL, SortBy: entities.SortByAddress, ToolVersion: "v1.2.3"}, goldenReports(1, 3, 0, 2))
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		So(lines, ShouldHaveLength, 5)
+
+		addresses := make([]string, 0)
+		for _, line := range lines[:4] {
+			record := make(map[string]interface{})
+			So(json.Unmarshal([]byte(line), &record), ShouldBeNil)
+			So(record["type"], ShouldEqual, entities.RecordTypeReport)
+			addresses = append(addresses, record["address"].(string))
+		}
+		So(addresses, ShouldResemble, []string{"aws_instance.web", "aws_iam_role.deploy", "aws_s3_bucket.logs", "aws_instance.api"})
+		So(lines[2], ShouldContainSubstring, `"differences":[{"attribute":"public_access_block.block_public_acls"`)
+
+		var summary entities.DriftRecord
+		So(json.Unmarshal([]byte(lines[4]), &summary), ShouldBeNil)
+		So(summary.Type, ShouldEqual, entities.RecordTypeSummary)
+		So(summary.DriftReport, ShouldBeNil)
+		So(summary.ToolVersion, ShouldEqual, "v1.2.3")
+		So(summary.Counts, ShouldResemble, map[string]int{"ok": 1, "drifted": 2, "error": 1, "total": 4})
+	})
+}
+
+func TestSARIFOutput(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	options := &entities.ReportOptions{
+		Output:    entities.OutputSARIF,
+		SortBy:    entities.SortByAddress,
+		Locations: map[string]*entities.SourceLocation{
+			"aws_instance.api":                    {File: "terraform/main.tf", Line: 12},
+			"module.network.aws_subnet.private":   {File: "terraform/modules/network/main.tf", Line: 3},
+			"module.network.aws_subnet.private[0]": {File: "terraform/wrong.tf", Line: 1},
+		},
+	}
+	startTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	metadata := &entities.ReportMetadata{StartTime: startTime, EndTime: startTime.Add(3 * time.Second), ToolVersion: "v1.2.3"}
+	sarif := func(reports ...*entities.DriftReport) *sarifLog {
+		r := newRenderer(options, io.Discard).(*sarifRenderer)
+		for _, report := range reports {
+			r.render(report)
+		}
+		return r.log(metadata)
+	}
+
+	Convey("the sarif log matches its golden file", t, func() {
+		reports := make([]*entities.DriftReport, 0)
+		for report := range goldenReports(0, 1, 2, 3) {
+			reports = append(reports, report)
+		}
+		sortReports(reports, entities.SortByAddress)
+		var buffer bytes.Buffer
+		r := newRenderer(options, &buffer)
+		for _, report := range reports {
+			r.render(report)
+		}
+		r.close(metadata)
+
+		path := filepath.Join("..", "testdata", "golden", "report_sarif.golden")
+		if *update {
+			So(os.WriteFile(path, buffer.Bytes(), 0o644), ShouldBeNil)
+		}
+		expected, err := os.ReadFile(path)
+		So(err, ShouldBeNil)
+		So(buffer.String(), ShouldEqual, string(expected))
 	})
+
+	Convey("differences and findings are results of their rule, errors are notifications", t, func() {
+		log := sarif(
+			&entities.DriftReport{
+				Address: "aws_instance.api[0]", ResourceType: "aws_instance", Status: entities.ReportStatusDrifted,
+				Differences: []*entities.Difference{{Attribute: "instance_type", Kind: entities.ChangeKindChanged, Expected: "t3.micro", Actual: "t3.large", Severity: entities.SeverityHigh}},
+				Suppressed:  []*entities.Difference{{Attribute: "tags.Owner", Kind: entities.ChangeKindRemoved, Expected: "ops", Actual: entities.MissingValue, Severity: entities.SeverityLow}},
+				Findings:    []*entities.Finding{{RuleID: "owner-tag", Description: "tag Owner must never be removed", Severity: entities.SeverityCritical}},
+			},
+			&entities.DriftReport{Address: "aws_iam_role.deploy", ResourceType: "aws_iam_role", Status: entities.ReportStatusError, Error: "throttled"},
+		)
+		So(log.Version, ShouldEqual, "2.1.0")
+		run := log.Runs[0]
+		So(run.Tool.Driver.Version, ShouldEqual, "v1.2.3")
+		ruleIDs := make([]string, 0)
+		for _, rule := range run.Tool.Driver.Rules {
+			ruleIDs = append(ruleIDs, rule.ID)
+		}
+		So(ruleIDs, ShouldResemble, []string{"custom/owner-tag", "drift/aws_instance/instance_type", "drift/aws_instance/tags.Owner"})
+
+		So(run.Results, ShouldHaveLength, 3)
+		So(run.Results[0].RuleIndex, ShouldEqual, 1)
+		So(run.Results[0].Level, ShouldEqual, "error")
+		So(run.Results[0].Message.Text, ShouldEqual, "aws_instance.api[0]: instance_type changed, AWS: t3.large, Terraform: t3.micro")
+		So(run.Results[0].Locations[0].LogicalLocations[0].FullyQualifiedName, ShouldEqual, "aws_instance.api[0]")
+		So(run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI, ShouldEqual, "terraform/main.tf")
+		So(run.Results[0].Locations[0].PhysicalLocation.Region.StartLine, ShouldEqual, 12)
+		So(run.Results[1].Level, ShouldEqual, "note")
+		So(run.Results[1].Suppressions, ShouldHaveLength, 1)
+		So(run.Results[2].RuleID, ShouldEqual, "custom/owner-tag")
+		So(run.Results[2].Level, ShouldEqual, "error")
+
+		So(run.Invocations[0].ExecutionSuccessful, ShouldBeFalse)
+		So(run.Invocations[0].ToolExecutionNotifications, ShouldHaveLength, 1)
+		So(run.Invocations[0].ToolExecutionNotifications[0].Locations[0].PhysicalLocation, ShouldBeNil)
+	})
+
+	Convey("the resources of modules are located at their block whatever the instance keys of the module", t, func() {
+		log := sarif(&entities.DriftReport{
+			Address: `module.network["eu-west-1"].aws_subnet.private[0]`, ResourceType: "aws_subnet", Status: entities.ReportStatusDrifted,
+			Differences: []*entities.Difference{{Attribute: "map_public_ip_on_launch", Kind: entities.ChangeKindChanged, Expected: "false", Actual: "true"}},
+		})
+		location := log.Runs[0].Results[0].Locations[0]
+		So(location.LogicalLocations[0].FullyQualifiedName, ShouldEqual, `module.network["eu-west-1"].aws_subnet.private[0]`)
+		So(location.PhysicalLocation.ArtifactLocation.URI, ShouldEqual, "terraform/modules/network/main.tf")
+		So(location.PhysicalLocation.Region.StartLine, ShouldEqual, 3)
+	})
+}
+
+func TestJUnitOutput(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	options := &entities.ReportOptions{Output: entities.OutputJUnit, SortBy: entities.SortByAddress}
+	startTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	metadata := &entities.ReportMetadata{StartTime: startTime, EndTime: startTime.Add(1500 * time.Millisecond), StateSources: []string{stateFile}}
+	junit := func() string {
+		reports := make([]*entities.DriftReport, 0)
+		for report := range goldenReports(0, 1, 2, 3) {
+			reports = append(reports, report)
+		}
+		sortReports(reports, entities.SortByAddress)
+		var buffer bytes.Buffer
+		r := newRenderer(options, &buffer)
+		for _, report := range reports {
+			r.render(report)
+		}
+		r.close(metadata)
+		return buffer.String()
+	}
+
+	Convey("the junit report matches its golden file", t, func() {
+		output := junit()
+		path := filepath.Join("..", "testdata", "golden", "report_junit.golden")
+		if *update {
+			So(os.WriteFile(path, []byte(output), 0o644), ShouldBeNil)
+		}
+		expected, err := os.ReadFile(path)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, string(expected))
+	})
+
+	Convey("a resource is a test case, drifted resources fail and errored checks are errors", t, func() {
+		var suites junitTestSuites
+		So(xml.Unmarshal([]byte(junit()), &suites), ShouldBeNil)
+		So(suites.Tests, ShouldEqual, 4)
+		So(suites.Failures, ShouldEqual, 2)
+		So(suites.Errors, ShouldEqual, 1)
+		So(suites.Time, ShouldEqual, "1.500")
+		So(suites.Suites, ShouldHaveLength, 1)
+
+		suite := suites.Suites[0]
+		So(suite.Name, ShouldEqual, stateFile)
+		So(suite.Cases[0].Name, ShouldEqual, "aws_iam_role.deploy")
+		So(suite.Cases[0].Error.Message, ShouldEqual, "failed with code 400: throttled")
+		So(suite.Cases[1].ClassName, ShouldEqual, "aws_instance")
+		So(suite.Cases[1].Failure.Message, ShouldEqual, "drifted on instance_type, tags.Name")
+		So(suite.Cases[1].Failure.Text, ShouldEqual, "instance_type (medium): AWS: t3.large, Terraform: t3.micro\ntags.Name (low): AWS: api-old, Terraform: api")
+		So(suite.Cases[2].Failure, ShouldBeNil)
+		So(suite.Cases[2].Error, ShouldBeNil)
+	})
+}
+
+func TestTemplateOutputs(t *testing.T) {
+	logger := utils.InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	startTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	metadata := &entities.ReportMetadata{
+		StartTime: startTime, EndTime: startTime.Add(3 * time.Second), ToolVersion: "v1.2.3",
+		StateSources: []string{stateFile}, Region: "us-east-1", Account: "123456789012",
+		Counts: map[string]int{"ok": 1, "drifted": 2, "error": 1, "total": 4},
+	}
+	output := func(format string, extra ...*entities.DriftReport) string {
+		options := &entities.ReportOptions{Output: format, SortBy: entities.SortByAddress}
+		reports := make([]*entities.DriftReport, 0)
+		for report := range goldenReports(0, 1, 2, 3) {
+			reports = append(reports, report)
+		}
+		reports = append(reports, extra...)
+		sortReports(reports, entities.SortByAddress)
+		var buffer bytes.Buffer
+		r := newRenderer(options, &buffer)
+		for _, report := range reports {
+			r.render(report)
+		}
+		r.close(metadata)
+		return buffer.String()
+	}
+
+	for _, format := range []string{entities.OutputMarkdown, entities.OutputHTML} {
+		Convey("the "+format+" report matches its golden file", t, func() {
+			rendered := output(format)
+			path := filepath.Join("..", "testdata", "golden", "report_"+format+".golden")
+			if *update {
+				So(os.WriteFile(path, []byte(rendered), 0o644), ShouldBeNil)
+			}
+			expected, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(rendered, ShouldEqual, string(expected))
+		})
+	}
+
+	Convey("values are escaped for their format", t, func() {
+		hostile := &entities.DriftReport{
+			ResourceID: "web", Address: "aws_lb_listener_rule.web", ResourceType: "aws_lb_listener_rule",
+			Drifted: true, Status: entities.ReportStatusDrifted, Severity: entities.SeverityHigh,
+			Differences: []*entities.Difference{
+				{Attribute: "condition.path_pattern./a|b", Kind: entities.ChangeKindAdded, Expected: entities.MissingValue, Actual: "<script>alert(1)</script>", Severity: entities.SeverityHigh},
+			},
+		}
+		markdown := output(entities.OutputMarkdown, hostile)
+		So(markdown, ShouldContainSubstring, "| condition.path_pattern./a\\|b | added | high | &lt;missing&gt; | &lt;script&gt;alert(1)&lt;/script&gt; |")
+		html := output(entities.OutputHTML, hostile)
+		So(html, ShouldNotContainSubstring, "<script>alert(1)")
+		So(html, ShouldContainSubstring, "&lt;script&gt;alert(1)&lt;/script&gt;")
+		So(html, ShouldContainSubstring, `<span class="missing">missing</span>`)
+	})
+}
+
+// goldenReports returns the same reports in the given order, with their differences in the order of the handlers
+// batchHandler is a handler of aws_instance resources in sync with AWS, it records the size of the batches it fetches
+// and fails the batch of the failing resource
+type batchHandler struct {
+	mu      sync.Mutex
+	sizes   []int
+	failing string
+}
+
+func (h *batchHandler) Types() []string {
+	return []string{"aws_instance"}
+}
+
+func (h *batchHandler) Normalize(state registry.State) ([]*registry.Resource, error) {
+	resources := make([]*registry.Resource, 0)
+	for _, stateResource := range state["aws_instance"] {
+		values := make(map[string]interface{})
+		if err := json.Unmarshal(stateResource.Instance.RawAttributes, &values); err != nil {
+			return nil, err
+		}
+		resources = append(resources, &registry.Resource{Type: "aws_instance", Address: stateResource.Address,
+			ID: values["id"].(string), Object: values, Values: values})
+	}
+	return resources, nil
+}
+
+func (h *batchHandler) Fetch(ctx context.Context, resources []*registry.Resource) (map[string]*registry.Resource, error) {
+	h.mu.Lock()
+	h.sizes = append(h.sizes, len(resources))
+	h.mu.Unlock()
+	awsResources := make(map[string]*registry.Resource, len(resources))
+	for _, resource := range resources {
+		if resource.ID == h.failing {
+			return nil, errors.New("throttled")
+		}
+		awsResources[resource.ID] = resource
+	}
+	return awsResources, nil
+}
+
+func (h *batchHandler) Compare(tfResource, awsResource *registry.Resource, options *entities.ReportOptions) ([]*entities.Difference, error) {
+	return []*entities.Difference{}, nil
+}
+
+func (h *batchHandler) batchSizes() []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sizes := append([]int{}, h.sizes...)
+	sort.Ints(sizes)
+	return sizes
+}
+
+func goldenReports(order ...int) <-chan *entities.DriftReport {
+	all := []*entities.DriftReport{
+		{
+			ResourceID: "logs-bucket", Address: "aws_s3_bucket.logs", ResourceType: "aws_s3_bucket",
+			Drifted: true, Status: entities.ReportStatusDrifted, Severity: entities.SeverityCritical,
+			Differences: []*entities.Difference{
+				{Attribute: "versioning.status", Kind: entities.ChangeKindChanged, Expected: "Enabled", Actual: "Suspended", Severity: entities.SeverityMedium},
+				{Attribute: "public_access_block.block_public_acls", Kind: entities.ChangeKindChanged, Expected: "true", Actual: "false", Severity: entities.SeverityCritical},
+				{Attribute: "tags.Team", Kind: entities.ChangeKindChanged, Expected: "data", Actual: "ops", Severity: entities.SeverityLow},
+			},
+		},
+		{
+			ResourceID: "i-0bbb", InstanceID: "i-0bbb", Address: "aws_instance.web", ResourceType: "aws_instance",
+			Status: entities.ReportStatusOK, Differences: []*entities.Difference{},
+		},
+		{
+			ResourceID: "i-0aaa", InstanceID: "i-0aaa", Address: "aws_instance.api", ResourceType: "aws_instance",
+			Drifted: true, Status: entities.ReportStatusDrifted, Severity: entities.SeverityMedium,
+			Differences: []*entities.Difference{
+				{Attribute: "tags.Name", Kind: entities.ChangeKindChanged, Expected: "api", Actual: "api-old", Severity: entities.SeverityLow},
+				{Attribute: "instance_type", Kind: entities.ChangeKindChanged, Expected: "t3.micro", Actual: "t3.large", Severity: entities.SeverityMedium},
+			},
+		},
+		{
+			ResourceID: "deploy", Address: "aws_iam_role.deploy", ResourceType: "aws_iam_role",
+			Status: entities.ReportStatusError, Error: "failed with code 400: throttled", Differences: []*entities.Difference{},
+		},
+	}
+	reports := make(chan *entities.DriftReport, len(all))
+	for _, i := range order {
+		reports <- all[i]
+	}
+	close(reports)
+	return reports
+}
+
+// render returns what the service prints for the reports
+func render(options *entities.ReportOptions, reports <-chan *entities.DriftReport) string {
+	svc := &AppDriftReportService{options: options}
+	var output bytes.Buffer
+	svc.render(reports, time.Now(), &output)
+	return output.String()
+}
+
+// differenceDetails maps the attribute paths of the differences to their printed form
+func differenceDetails(differences []*entities.Difference) map[string]string {
+	details := make(map[string]string)
+	for _, difference := range differences {
+		details[difference.Attribute] = difference.String()
+	}
+	return details
 }
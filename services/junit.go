@@ -0,0 +1,128 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/driftreport/entities"
+)
+
+type (
+	// junitRenderer prints the reports as a JUnit XML report for the test results of CI systems, with a test suite
+	// per state file and a test case per resource. A drifted resource is a failure listing its differences and a
+	// resource that could not be checked is an error
+	junitRenderer struct {
+		w       io.Writer
+		reports []*entities.DriftReport
+	}
+
+	junitTestSuites struct {
+		XMLName  xml.Name          `xml:"testsuites"`
+		Name     string            `xml:"name,attr"`
+		Tests    int               `xml:"tests,attr"`
+		Failures int               `xml:"failures,attr"`
+		Errors   int               `xml:"errors,attr"`
+		Time     string            `xml:"time,attr"`
+		Suites   []*junitTestSuite `xml:"testsuite"`
+	}
+
+	junitTestSuite struct {
+		Name      string           `xml:"name,attr"`
+		Tests     int              `xml:"tests,attr"`
+		Failures  int              `xml:"failures,attr"`
+		Errors    int              `xml:"errors,attr"`
+		Time      string           `xml:"time,attr"`
+		Timestamp string           `xml:"timestamp,attr"`
+		Cases     []*junitTestCase `xml:"testcase"`
+	}
+
+	junitTestCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Failure   *junitProblem `xml:"failure,omitempty"`
+		Error     *junitProblem `xml:"error,omitempty"`
+	}
+
+	junitProblem struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+func (r *junitRenderer) streamed() bool {
+	return false
+}
+
+func (r *junitRenderer) open() {}
+
+func (r *junitRenderer) render(report *entities.DriftReport) {
+	r.reports = append(r.reports, report)
+}
+
+func (r *junitRenderer) close(metadata *entities.ReportMetadata) {
+	output, _ := xml.MarshalIndent(r.testSuites(metadata), "", "  ")
+	fmt.Fprintln(r.w, xml.Header+string(output))
+}
+
+// testSuites builds the JUnit report. The service checks a single state file, so every report is a test case of the
+// suite of the first state source
+func (r *junitRenderer) testSuites(metadata *entities.ReportMetadata) *junitTestSuites {
+	duration := fmt.Sprintf("%.3f", metadata.EndTime.Sub(metadata.StartTime).Seconds())
+	suite := &junitTestSuite{
+		Time:      duration,
+		Timestamp: metadata.StartTime.Format("2006-01-02T15:04:05"),
+		Cases:     make([]*junitTestCase, 0, len(r.reports)),
+	}
+	if len(metadata.StateSources) > 0 {
+		suite.Name = metadata.StateSources[0]
+	}
+	for _, report := range r.reports {
+		testCase := &junitTestCase{Name: report.Address, ClassName: report.ResourceType}
+		switch {
+		case report.Status == entities.ReportStatusError:
+			testCase.Error = &junitProblem{Message: report.Error, Type: entities.ReportStatusError}
+			suite.Errors++
+		case report.Drifted:
+			testCase.Failure = driftFailure(report)
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Tests = len(suite.Cases)
+
+	return &junitTestSuites{
+		Name:     toolName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     duration,
+		Suites:   []*junitTestSuite{suite},
+	}
+}
+
+// driftFailure returns the failure of a drifted report, the message names the drifted attributes and the text has a
+// line per difference and finding like the rows of the table
+func driftFailure(report *entities.DriftReport) *junitProblem {
+	attributes := make([]string, 0, len(report.Differences)+len(report.Findings))
+	lines := make([]string, 0, len(report.Differences)+len(report.Findings))
+	for _, difference := range report.Differences {
+		attributes = append(attributes, difference.Attribute)
+		line := fmt.Sprintf("%s (%s): %s", difference.Attribute, difference.Severity, difference)
+		if difference.Confidence != "" {
+			line += fmt.Sprintf(" [%s]", difference.Confidence)
+		}
+		lines = append(lines, line)
+	}
+	for _, finding := range report.Findings {
+		attributes = append(attributes, finding.RuleID)
+		lines = append(lines, fmt.Sprintf("rule %s (%s): %s", finding.RuleID, finding.Severity, finding.Description))
+	}
+	return &junitProblem{
+		Message: "drifted on " + strings.Join(attributes, ", "),
+		Type:    entities.ReportStatusDrifted,
+		Text:    strings.Join(lines, "\n"),
+	}
+}
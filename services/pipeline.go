@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/registry"
+	"github.com/driftreport/rules"
+	"github.com/driftreport/utils"
+)
+
+const (
+	// defaultWorkers is the size of the worker pools when the report options do not set it
+	defaultWorkers = 8
+	// defaultBatchSize is the number of resources fetched by a call of a handler when the report options do not set it
+	defaultBatchSize = 100
+)
+
+// pipeline runs the stages of a drift report, fetch → compare → render, connected by channels holding at most one
+// item per worker, so that the memory does not grow with the number of resources and the first reports are rendered
+// while the next resources are fetched. Every resource reaches the render stage, a resource that could not be
+// checked as an error report
+type pipeline struct {
+	workers   int
+	batchSize int
+}
+
+// fetchBatch is a batch of the resources of a handler fetched by a single call
+type fetchBatch struct {
+	handler   registry.Handler
+	resources []*registry.Resource
+}
+
+func newPipeline(workers, batchSize int) *pipeline {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	return &pipeline{workers: workers, batchSize: batchSize}
+}
+
+// fetch normalizes the resources of the handlers, fetches them in batches of at most batchSize resources with a pool
+// of workers and sends their checks, so that the resources of a large handler are fetched in parallel and compared
+// before the last ones are fetched. The resources of a batch that fails get checks returning its error. The channel
+// is closed once every batch is done
+func (s *AppDriftReportService) fetch(ctx context.Context, p *pipeline, handlers []registry.Handler, state registry.State, ruleEngine *rules.Engine) <-chan resourceCheck {
+	queue := make(chan fetchBatch)
+	checks := make(chan resourceCheck, p.workers)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(queue)
+		for _, handler := range handlers {
+			resources, err := handler.Normalize(state)
+			if err != nil {
+				utils.Logger.Sugar().Errorf("error reading the resources of %s from the state with err %v", strings.Join(handler.Types(), ", "), err)
+				for _, check := range failedChecks(handler, state, err) {
+					checks <- check
+				}
+				continue
+			}
+			for _, batch := range p.batches(handler, resources) {
+				queue <- batch
+			}
+		}
+	}()
+
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range queue {
+				batchChecks, err := s.resourceChecks(ctx, batch.handler, batch.resources, ruleEngine)
+				if err != nil {
+					utils.Logger.Sugar().Errorf("error retrieving AWS resources of %s with err %v", strings.Join(batch.handler.Types(), ", "), err)
+					batchChecks = failedResourceChecks(batch.resources, err)
+				}
+				for _, check := range batchChecks {
+					checks <- check
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(checks)
+	}()
+	return checks
+}
+
+// batches splits the resources of a handler in batches of at most batchSize resources. The resources of a handler
+// listing the unmanaged resources are a single batch, as it needs every resource to tell the unmanaged ones apart
+func (p *pipeline) batches(handler registry.Handler, resources []*registry.Resource) []fetchBatch {
+	if _, ok := handler.(registry.UnmanagedLister); ok {
+		return []fetchBatch{{handler: handler, resources: resources}}
+	}
+	batches := make([]fetchBatch, 0, (len(resources)+p.batchSize-1)/p.batchSize)
+	for start := 0; start < len(resources); start += p.batchSize {
+		end := min(start+p.batchSize, len(resources))
+		batches = append(batches, fetchBatch{handler: handler, resources: resources[start:end]})
+	}
+	return batches
+}
+
+// compare runs the checks with a pool of workers and sends their reports. A failing check, or a check left when the
+// context is done, is sent as an error report. The channel is closed once every check is done
+func (s *AppDriftReportService) compare(ctx context.Context, p *pipeline, checks <-chan resourceCheck) <-chan *entities.DriftReport {
+	reports := make(chan *entities.DriftReport, p.workers)
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for check := range checks {
+				if err := ctx.Err(); err != nil {
+					reports <- errorReport(check, err)
+					continue
+				}
+				report, err := check.run()
+				if err != nil {
+					utils.Logger.Sugar().Errorf("error checking drift for resource %s: %v", check.address, err)
+					report = errorReport(check, err)
+				}
+				reports <- report
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(reports)
+	}()
+	return reports
+}
+
+// failedChecks returns a check per resource of the state the handler failed to read, which returns the error
+func failedChecks(handler registry.Handler, state registry.State, err error) []resourceCheck {
+	checks := make([]resourceCheck, 0)
+	for _, resourceType := range handler.Types() {
+		for _, stateResource := range state[resourceType] {
+			checks = append(checks, resourceCheck{
+				address:      stateResource.Address,
+				resourceType: resourceType,
+				run: func() (*entities.DriftReport, error) {
+					return nil, err
+				},
+			})
+		}
+	}
+	return checks
+}
+
+// failedResourceChecks returns a check per resource of a batch the handler failed to fetch, which returns the error
+func failedResourceChecks(resources []*registry.Resource, err error) []resourceCheck {
+	checks := make([]resourceCheck, 0, len(resources))
+	for _, resource := range resources {
+		checks = append(checks, resourceCheck{
+			address:      resource.Address,
+			resourceType: resource.Type,
+			id:           resource.ID,
+			run: func() (*entities.DriftReport, error) {
+				return nil, err
+			},
+		})
+	}
+	return checks
+}
+
+// errorReport reports a resource that could not be checked, it is neither in sync nor drifted
+func errorReport(check resourceCheck, err error) *entities.DriftReport {
+	resourceID := check.id
+	if resourceID == "" {
+		resourceID = check.address
+	}
+	return &entities.DriftReport{
+		ResourceID:   resourceID,
+		Address:      check.address,
+		ResourceType: check.resourceType,
+		Status:       entities.ReportStatusError,
+		Error:        err.Error(),
+		Differences:  make([]*entities.Difference, 0),
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/driftreport/entities"
+)
+
+// toolName names the tool in the outputs describing it
+const toolName = "driftreport"
+
+type (
+	// renderer prints the reports of a run in an output format. The render stage calls open, then render for every
+	// report, in the order they are compared when the renderer is streamed and sorted otherwise, then close
+	renderer interface {
+		streamed() bool
+		open()
+		render(report *entities.DriftReport)
+		close(metadata *entities.ReportMetadata)
+	}
+
+	// textRenderer prints every report in JSON, then their table. Only the rows of the table are kept, apart from a
+	// table grouped by severity which needs the reports
+	textRenderer struct {
+		w       io.Writer
+		stream  bool
+		groupBy string
+		rows    []string
+		reports []*entities.DriftReport
+	}
+
+	// documentRenderer prints the reports as a single JSON document with the metadata of the run
+	documentRenderer struct {
+		w       io.Writer
+		reports []*entities.DriftReport
+	}
+
+	// jsonlRenderer prints a JSON line per report as soon as it is compared, and a summary line once every report is
+	// printed
+	jsonlRenderer struct {
+		encoder *json.Encoder
+	}
+)
+
+// newRenderer returns the renderer of the output option, writing to w
+func newRenderer(options *entities.ReportOptions, w io.Writer) renderer {
+	switch options.Output {
+	case entities.OutputJSON:
+		return &documentRenderer{w: w, reports: make([]*entities.DriftReport, 0)}
+	case entities.OutputJSONL:
+		return &jsonlRenderer{encoder: json.NewEncoder(w)}
+	case entities.OutputSARIF:
+		return &sarifRenderer{w: w, options: options, reports: make([]*entities.DriftReport, 0)}
+	case entities.OutputJUnit:
+		return &junitRenderer{w: w, reports: make([]*entities.DriftReport, 0)}
+	case entities.OutputMarkdown:
+		return &templateRenderer{w: w, template: markdownTemplate, reports: make([]*entities.DriftReport, 0)}
+	case entities.OutputHTML:
+		return &templateRenderer{w: w, template: htmlTemplate, reports: make([]*entities.DriftReport, 0)}
+	default:
+		return &textRenderer{
+			w:       w,
+			stream:  options.SortBy == entities.SortByNone && options.GroupBy == "",
+			groupBy: options.GroupBy,
+			rows:    make([]string, 0),
+		}
+	}
+}
+
+func (r *textRenderer) streamed() bool {
+	return r.stream
+}
+
+func (r *textRenderer) open() {
+	fmt.Fprintln(r.w, "\nPrint drift reports in JSON")
+}
+
+func (r *textRenderer) render(report *entities.DriftReport) {
+	printDriftJSON(r.w, report)
+	if r.groupBy == entities.GroupBySeverity {
+		r.reports = append(r.reports, report)
+		return
+	}
+	r.rows = append(r.rows, driftRow(report))
+}
+
+func (r *textRenderer) close(_ *entities.ReportMetadata) {
+	fmt.Fprintln(r.w, "\nPrint drift reports in tabular format")
+	if r.groupBy != entities.GroupBySeverity {
+		printDriftRows(r.w, r.rows)
+		return
+	}
+	for _, group := range groupBySeverity(r.reports) {
+		fmt.Fprintf(r.w, "\nSeverity: %s\n", group.label)
+		printDriftTable(r.w, group.reports)
+	}
+}
+
+func (r *documentRenderer) streamed() bool {
+	return false
+}
+
+func (r *documentRenderer) open() {}
+
+func (r *documentRenderer) render(report *entities.DriftReport) {
+	r.reports = append(r.reports, report)
+}
+
+func (r *documentRenderer) close(metadata *entities.ReportMetadata) {
+	printDriftDocument(r.w, &entities.DriftDocument{Metadata: metadata, Reports: r.reports})
+}
+
+func (r *jsonlRenderer) streamed() bool {
+	return true
+}
+
+func (r *jsonlRenderer) open() {}
+
+func (r *jsonlRenderer) render(report *entities.DriftReport) {
+	_ = r.encoder.Encode(&entities.DriftRecord{Type: entities.RecordTypeReport, DriftReport: report})
+}
+
+func (r *jsonlRenderer) close(metadata *entities.ReportMetadata) {
+	_ = r.encoder.Encode(&entities.DriftRecord{Type: entities.RecordTypeSummary, ReportMetadata: metadata})
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"github.com/driftreport/entities"
+	"github.com/driftreport/rules"
+)
+
+// reportBuilder collects the differences of a resource and applies the ignore rules, the drift policy and the
+// custom rules, so that every resource type is reported the same way
+type reportBuilder struct {
+	report     *entities.DriftReport
+	options    *entities.ReportOptions
+	ruleEngine *rules.Engine
+}
+
+func newReportBuilder(resourceType, address, resourceID string, options *entities.ReportOptions, ruleEngine *rules.Engine) *reportBuilder {
+	report := &entities.DriftReport{
+		ResourceID:   resourceID,
+		Address:      address,
+		ResourceType: resourceType,
+		Differences:  make([]*entities.Difference, 0),
+	}
+	// instance reports keep the instance_id they had before other resource types were supported
+	if resourceType == "aws_instance" {
+		report.InstanceID = resourceID
+	}
+	return &reportBuilder{
+		report:     report,
+		options:    options,
+		ruleEngine: ruleEngine,
+	}
+}
+
+// add classifies the differences and sets aside the ones matched by an ignore rule
+func (b *reportBuilder) add(differences ...*entities.Difference) {
+	for _, difference := range differences {
+		difference.Severity = b.options.Policy.Classify(b.report.ResourceType, difference)
+		if rule := b.options.IgnoreRules.Match(b.report.Address, difference.Attribute); rule != nil {
+			b.report.Suppressed = append(b.report.Suppressed, difference)
+			continue
+		}
+		b.report.Differences = append(b.report.Differences, difference)
+	}
+}
+
+// build evaluates the custom rules against the terraform and aws values of the resource and completes the report.
+// Differences allowed by a passing rule are moved to the suppressed list
+func (b *reportBuilder) build(tfValues, awsValues map[string]interface{}) *entities.DriftReport {
+	report := b.report
+	result := b.ruleEngine.Evaluate(report.ResourceType, report.Address, tfValues, awsValues)
+	if len(result.Allowed) > 0 {
+		allowed := &entities.IgnoreRules{Rules: []*entities.IgnoreRule{{Attributes: result.Allowed}}}
+		remaining := make([]*entities.Difference, 0, len(report.Differences))
+		for _, difference := range report.Differences {
+			if allowed.Match(report.Address, difference.Attribute) != nil {
+				report.Suppressed = append(report.Suppressed, difference)
+			} else {
+				remaining = append(remaining, difference)
+			}
+		}
+		report.Differences = remaining
+	}
+	report.Findings = result.Findings
+
+	report.Drifted = len(report.Differences) > 0 || len(report.Findings) > 0
+	report.Status = entities.ReportStatusOK
+	if report.Drifted {
+		report.Status = entities.ReportStatusDrifted
+	}
+	for _, difference := range report.Differences {
+		if difference.Severity.Rank() > report.Severity.Rank() {
+			report.Severity = difference.Severity
+		}
+	}
+	for _, finding := range report.Findings {
+		if finding.Severity.Rank() > report.Severity.Rank() {
+			report.Severity = finding.Severity
+		}
+	}
+	return report
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/driftreport/entities"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+type (
+	// sarifRenderer prints the reports as a SARIF 2.1.0 log with a result per difference and finding. The rule of a
+	// difference is its resource type and attribute, the rule of a finding its custom rule, and the resources that
+	// could not be checked are notifications of the run
+	sarifRenderer struct {
+		w       io.Writer
+		options *entities.ReportOptions
+		reports []*entities.DriftReport
+	}
+
+	sarifLog struct {
+		Schema  string      `json:"$schema"`
+		Version string      `json:"version"`
+		Runs    []*sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool        *sarifTool         `json:"tool"`
+		Invocations []*sarifInvocation `json:"invocations"`
+		Results     []*sarifResult     `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver *sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name    string       `json:"name"`
+		Version string       `json:"version,omitempty"`
+		Rules   []*sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID                   string              `json:"id"`
+		ShortDescription     *sarifMessage       `json:"shortDescription"`
+		DefaultConfiguration *sarifConfiguration `json:"defaultConfiguration"`
+	}
+
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+
+	sarifInvocation struct {
+		ExecutionSuccessful        bool                 `json:"executionSuccessful"`
+		StartTimeUTC               string               `json:"startTimeUtc"`
+		EndTimeUTC                 string               `json:"endTimeUtc"`
+		ToolExecutionNotifications []*sarifNotification `json:"toolExecutionNotifications"`
+	}
+
+	sarifNotification struct {
+		Level     string           `json:"level"`
+		Message   *sarifMessage    `json:"message"`
+		Locations []*sarifLocation `json:"locations"`
+	}
+
+	sarifResult struct {
+		RuleID              string             `json:"ruleId"`
+		RuleIndex           int                `json:"ruleIndex"`
+		Level               string             `json:"level"`
+		Message             *sarifMessage      `json:"message"`
+		Locations           []*sarifLocation   `json:"locations"`
+		PartialFingerprints map[string]string  `json:"partialFingerprints"`
+		Suppressions        []*sarifSuppressed `json:"suppressions,omitempty"`
+		Properties          map[string]string  `json:"properties,omitempty"`
+	}
+
+	sarifSuppressed struct {
+		Kind string `json:"kind"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+		LogicalLocations []*sarifLogical        `json:"logicalLocations"`
+	}
+
+	sarifPhysicalLocation struct {
+		ArtifactLocation *sarifArtifact `json:"artifactLocation"`
+		Region           *sarifRegion   `json:"region"`
+	}
+
+	sarifArtifact struct {
+		URI string `json:"uri"`
+	}
+
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+
+	sarifLogical struct {
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+		Kind               string `json:"kind"`
+	}
+)
+
+func (r *sarifRenderer) streamed() bool {
+	return false
+}
+
+func (r *sarifRenderer) open() {}
+
+func (r *sarifRenderer) render(report *entities.DriftReport) {
+	r.reports = append(r.reports, report)
+}
+
+func (r *sarifRenderer) close(metadata *entities.ReportMetadata) {
+	output, _ := json.MarshalIndent(r.log(metadata), "", "  ")
+	fmt.Fprintln(r.w, string(output))
+}
+
+// log builds the SARIF log of the reports, the rules are sorted by id and the results follow the reports
+func (r *sarifRenderer) log(metadata *entities.ReportMetadata) *sarifLog {
+	rules := make(map[string]*sarifRule)
+	results := make([]*sarifResult, 0)
+	notifications := make([]*sarifNotification, 0)
+	for _, report := range r.reports {
+		if report.Status == entities.ReportStatusError {
+			notifications = append(notifications, &sarifNotification{
+				Level:     "error",
+				Message:   &sarifMessage{Text: fmt.Sprintf("%s could not be checked: %s", report.Address, report.Error)},
+				Locations: r.locations(report),
+			})
+			continue
+		}
+		for _, difference := range report.Differences {
+			results = append(results, r.differenceResult(rules, report, difference, false))
+		}
+		for _, difference := range report.Suppressed {
+			results = append(results, r.differenceResult(rules, report, difference, true))
+		}
+		for _, finding := range report.Findings {
+			ruleID := "custom/" + finding.RuleID
+			addRule(rules, ruleID, finding.Description, finding.Severity)
+			results = append(results, &sarifResult{
+				RuleID:              ruleID,
+				Level:               sarifLevel(finding.Severity),
+				Message:             &sarifMessage{Text: fmt.Sprintf("%s: %s", report.Address, finding.Description)},
+				Locations:           r.locations(report),
+				PartialFingerprints: map[string]string{"driftFinding/v1": report.Address + "|" + finding.RuleID},
+				Properties:          map[string]string{"severity": string(finding.Severity)},
+			})
+		}
+	}
+
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	driverRules := make([]*sarifRule, 0, len(ids))
+	indexes := make(map[string]int, len(ids))
+	for i, id := range ids {
+		driverRules = append(driverRules, rules[id])
+		indexes[id] = i
+	}
+	for _, result := range results {
+		result.RuleIndex = indexes[result.RuleID]
+	}
+
+	return &sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []*sarifRun{{
+			Tool: &sarifTool{Driver: &sarifDriver{Name: toolName, Version: metadata.ToolVersion, Rules: driverRules}},
+			Invocations: []*sarifInvocation{{
+				ExecutionSuccessful:        len(notifications) == 0,
+				StartTimeUTC:               metadata.StartTime.Format("2006-01-02T15:04:05.000Z"),
+				EndTimeUTC:                 metadata.EndTime.Format("2006-01-02T15:04:05.000Z"),
+				ToolExecutionNotifications: notifications,
+			}},
+			Results: results,
+		}},
+	}
+}
+
+// differenceResult returns the result of a difference, a difference matched by an ignore rule is suppressed
+func (r *sarifRenderer) differenceResult(rules map[string]*sarifRule, report *entities.DriftReport, difference *entities.Difference, suppressed bool) *sarifResult {
+	ruleID := "drift/" + report.ResourceType + "/" + difference.Attribute
+	addRule(rules, ruleID, fmt.Sprintf("%s drifted on %s", report.ResourceType, difference.Attribute), difference.Severity)
+	result := &sarifResult{
+		RuleID: ruleID,
+		Level:  sarifLevel(difference.Severity),
+		Message: &sarifMessage{Text: fmt.Sprintf("%s: %s %s, %s", report.Address, difference.Attribute, difference.Kind,
+			difference.String())},
+		Locations:           r.locations(report),
+		PartialFingerprints: map[string]string{"driftDifference/v1": report.Address + "|" + difference.Attribute},
+		Properties:          map[string]string{"kind": difference.Kind},
+	}
+	if difference.Severity != "" {
+		result.Properties["severity"] = string(difference.Severity)
+	}
+	if difference.Confidence != "" {
+		result.Properties["confidence"] = difference.Confidence
+	}
+	if suppressed {
+		result.Suppressions = []*sarifSuppressed{{Kind: "external"}}
+	}
+	return result
+}
+
+// locations returns the location of a report, its resource address and the resource block when the terraform code is
+// scanned. The block of an instance of a resource or module with count or for_each is the block of the resource
+func (r *sarifRenderer) locations(report *entities.DriftReport) []*sarifLocation {
+	location := &sarifLocation{
+		LogicalLocations: []*sarifLogical{{FullyQualifiedName: report.Address, Kind: "resource"}},
+	}
+	if source, ok := r.options.Locations[entities.ConfigAddress(report.Address)]; ok {
+		location.PhysicalLocation = &sarifPhysicalLocation{
+			ArtifactLocation: &sarifArtifact{URI: source.File},
+			Region:           &sarifRegion{StartLine: source.Line},
+		}
+	}
+	return []*sarifLocation{location}
+}
+
+// addRule adds the rule of a result, at the highest severity of its results
+func addRule(rules map[string]*sarifRule, id, description string, severity entities.Severity) {
+	rule, ok := rules[id]
+	if !ok {
+		rules[id] = &sarifRule{
+			ID:                   id,
+			ShortDescription:     &sarifMessage{Text: description},
+			DefaultConfiguration: &sarifConfiguration{Level: sarifLevel(severity)},
+		}
+		return
+	}
+	if sarifLevelRank(sarifLevel(severity)) > sarifLevelRank(rule.DefaultConfiguration.Level) {
+		rule.DefaultConfiguration.Level = sarifLevel(severity)
+	}
+}
+
+// sarifLevel maps a severity to a SARIF level, a difference without a severity is a warning
+func sarifLevel(severity entities.Severity) string {
+	switch severity {
+	case entities.SeverityCritical, entities.SeverityHigh:
+		return "error"
+	case entities.SeverityLow, entities.SeverityInfo:
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+func sarifLevelRank(level string) int {
+	return map[string]int{"note": 1, "warning": 2, "error": 3}[level]
+}
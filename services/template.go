@@ -0,0 +1,80 @@
+package services
+
+import (
+	"embed"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/driftreport/entities"
+	"github.com/driftreport/utils"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// templateFuncs are the functions of the report templates
+var templateFuncs = map[string]interface{}{
+	"severity": severityText,
+	"time": func(t time.Time) string {
+		return t.UTC().Format(time.RFC1123)
+	},
+	"missing": func(value string) bool {
+		return value == entities.MissingValue
+	},
+	"cell": markdownCell,
+}
+
+var (
+	markdownTemplate = template.Must(template.New("report.md.tmpl").Funcs(templateFuncs).ParseFS(templates, "templates/report.md.tmpl"))
+	htmlTemplate     = htmltemplate.Must(htmltemplate.New("report.html.tmpl").Funcs(templateFuncs).ParseFS(templates, "templates/report.html.tmpl"))
+
+	markdownCellReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "|", `\|`, "\r\n", "<br>", "\n", "<br>")
+)
+
+type (
+	// reportTemplate is a text or html template executed with the drift document
+	reportTemplate interface {
+		Execute(w io.Writer, data any) error
+	}
+
+	// templateRenderer prints the reports with a template of the templates directory, from the same document as the
+	// json output: the markdown template for PR comments and the self-contained html template for emails and browsers
+	templateRenderer struct {
+		w        io.Writer
+		template reportTemplate
+		reports  []*entities.DriftReport
+	}
+)
+
+func (r *templateRenderer) streamed() bool {
+	return false
+}
+
+func (r *templateRenderer) open() {}
+
+func (r *templateRenderer) render(report *entities.DriftReport) {
+	r.reports = append(r.reports, report)
+}
+
+func (r *templateRenderer) close(metadata *entities.ReportMetadata) {
+	if err := r.template.Execute(r.w, &entities.DriftDocument{Metadata: metadata, Reports: r.reports}); err != nil {
+		utils.Logger.Sugar().Errorf("error rendering the drift report template: %v", err)
+	}
+}
+
+// severityText prints a missing severity as - like the table
+func severityText(severity entities.Severity) string {
+	if severity == "" {
+		return "-"
+	}
+	return string(severity)
+}
+
+// markdownCell escapes a value for a cell of a markdown table, the HTML characters are escaped so that values like
+// <missing> are printed and the line breaks are kept with <br>
+func markdownCell(value string) string {
+	return markdownCellReplacer.Replace(value)
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/driftreport/entities"
+)
+
+// CompareNested walks the nested lists and objects of the AWS value and compares every leaf with the terraform value
+// at the same path. It returns the differing attributes with their path, e.g. metadata_options[0].http_tokens.
+// Only the keys present on the AWS side are compared, so terraform attributes that are not read from AWS are skipped
+func CompareNested(path string, awsValue, tfValue interface{}) []*entities.Difference {
+	differences := make([]*entities.Difference, 0)
+	compareNested(path, awsValue, tfValue, &differences)
+	return differences
+}
+
+func compareNested(path string, awsValue, tfValue interface{}, differences *[]*entities.Difference) {
+	switch aws := awsValue.(type) {
+	case map[string]interface{}:
+		tf, _ := tfValue.(map[string]interface{})
+		for key, value := range aws {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			compareNested(childPath, value, tf[key], differences)
+		}
+	case []interface{}:
+		tf, _ := tfValue.([]interface{})
+		for i := 0; i < max(len(aws), len(tf)); i++ {
+			elementPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(aws):
+				*differences = append(*differences, entities.NewDifference(elementPath, tf[i], nil))
+			case i >= len(tf):
+				*differences = append(*differences, entities.NewDifference(elementPath, nil, aws[i]))
+			default:
+				compareNested(elementPath, aws[i], tf[i], differences)
+			}
+		}
+	default:
+		if !scalarEqual(awsValue, tfValue) {
+			*differences = append(*differences, entities.NewDifference(path, tfValue, awsValue))
+		}
+	}
+}
+
+// scalarEqual compares leaf values by their printed form, so that the int32 of the AWS SDK equals the float64 decoded
+// from the state json, and treats an unset value as an empty string
+func scalarEqual(awsValue, tfValue interface{}) bool {
+	if awsValue == nil {
+		awsValue = ""
+	}
+	if tfValue == nil {
+		tfValue = ""
+	}
+	return fmt.Sprintf("%v", awsValue) == fmt.Sprintf("%v", tfValue)
+}
+
+// SetDifferences compares two unordered lists, every value only in AWS is added and every value only in terraform
+// is removed, with the value as the last element of the attribute path
+func SetDifferences(attribute string, awsValues, tfValues []string) []*entities.Difference {
+	differences := make([]*entities.Difference, 0)
+	for _, value := range awsValues {
+		if !slices.Contains(tfValues, value) {
+			differences = append(differences, entities.NewDifference(attribute+"."+value, nil, value))
+		}
+	}
+	for _, value := range tfValues {
+		if !slices.Contains(awsValues, value) {
+			differences = append(differences, entities.NewDifference(attribute+"."+value, value, nil))
+		}
+	}
+	return differences
+}
+
+// TagDifferences returns the tags whose values differ between AWS and terraform. AWS returns the tags merged with
+// the provider default_tags, so they are compared with tags_all when the state has it. Differences are named
+// tags.<key> for tags set on the resource and default_tags.<key> for tags coming from the provider
+func TagDifferences(awsTags, tags, tagsAll map[string]string, includeReserved bool) []*entities.Difference {
+	tfTags := MergedTags(tags, tagsAll)
+	tagPath := func(key string) string {
+		if _, ok := tags[key]; ok || tagsAll == nil {
+			return "tags." + key
+		}
+		return "default_tags." + key
+	}
+
+	differences := make([]*entities.Difference, 0)
+	for key, tfValue := range tfTags {
+		if !includeReserved && strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		awsValue, ok := awsTags[key]
+		if !ok {
+			differences = append(differences, entities.NewDifference(tagPath(key), tfValue, nil))
+		} else if awsValue != tfValue {
+			differences = append(differences, entities.NewDifference(tagPath(key), tfValue, awsValue))
+		}
+	}
+	for key, awsValue := range awsTags {
+		if !includeReserved && strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		if _, ok := tfTags[key]; !ok {
+			differences = append(differences, entities.NewDifference("tags."+key, nil, awsValue))
+		}
+	}
+	return differences
+}
+
+// MergedTags returns tags_all when the state has it, it includes the provider default_tags
+func MergedTags(tags, tagsAll map[string]string) map[string]string {
+	if tagsAll != nil {
+		return tagsAll
+	}
+	return tags
+}
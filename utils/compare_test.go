@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompareNested(t *testing.T) {
+	Convey("compare nested blocks by attribute path", t, func() {
+		awsValue := []interface{}{map[string]interface{}{
+			"http_tokens":                 "required",
+			"http_put_response_hop_limit": int32(1),
+			"kms_key_id":                  "",
+		}}
+		tfValue := []interface{}{map[string]interface{}{
+			"http_tokens":                 "optional",
+			"http_put_response_hop_limit": float64(1),
+			"kms_key_id":                  nil,
+			"instance_metadata_tags":      "disabled",
+		}}
+		differences := CompareNested("metadata_options", awsValue, tfValue)
+		So(len(differences), ShouldEqual, 1)
+		So(differences[0].Attribute, ShouldEqual, "metadata_options[0].http_tokens")
+		So(differences[0].Kind, ShouldEqual, "changed")
+		So(differences[0].String(), ShouldEqual, "AWS: required, Terraform: optional")
+	})
+
+	Convey("extra and missing list elements", t, func() {
+		awsValue := []interface{}{
+			map[string]interface{}{"device_name": "/dev/sdb"},
+			map[string]interface{}{"device_name": "/dev/sdc"},
+		}
+		tfValue := []interface{}{map[string]interface{}{"device_name": "/dev/sdb"}}
+		differences := CompareNested("ebs_block_device", awsValue, tfValue)
+		So(len(differences), ShouldEqual, 1)
+		So(differences[0].Attribute, ShouldEqual, "ebs_block_device[1]")
+		So(differences[0].Kind, ShouldEqual, "added")
+		So(differences[0].Expected, ShouldEqual, "<missing>")
+	})
+}
+
+func TestNormalizeJSON(t *testing.T) {
+	Convey("documents only differing in formatting are equal", t, func() {
+		first, err := NormalizeJSON(`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:GetObject"}]}`)
+		So(err, ShouldBeNil)
+		second, err := NormalizeJSON("{\n  \"Statement\": [\n    {\"Action\": \"s3:GetObject\", \"Effect\": \"Allow\"}\n  ],\n  \"Version\": \"2012-10-17\"\n}")
+		So(err, ShouldBeNil)
+		So(first, ShouldEqual, second)
+
+		empty, err := NormalizeJSON(" ")
+		So(err, ShouldBeNil)
+		So(empty, ShouldEqual, "")
+
+		_, err = NormalizeJSON("{")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,335 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/driftreport/entities"
+)
+
+var (
+	resourceBlockRegex = regexp.MustCompile(`^\s*resource\s+"([^"]+)"\s+"([^"]+)"`)
+	moduleBlockRegex   = regexp.MustCompile(`^\s*module\s+"([^"]+)"`)
+	moduleSourceRegex  = regexp.MustCompile(`^\s*source\s*=\s*"([^"]+)"`)
+	ignoreChangesRegex = regexp.MustCompile(`ignore_changes\s*=\s*(all|\[)`)
+	blockCommentRegex  = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	indexKeyRegex      = regexp.MustCompile(`\[\s*"([^"]*)"\s*\]`)
+)
+
+// ParseLifecycleIgnoreChanges scans the .tf files of a directory and of the modules it calls for lifecycle
+// ignore_changes declarations and returns them as ignore rules keyed by resource address, e.g.
+// module.network.aws_subnet.private. It is a line based scanner, not a full HCL parser
+func ParseLifecycleIgnoreChanges(dir string) ([]*entities.IgnoreRule, error) {
+	rules := make([]*entities.IgnoreRule, 0)
+	if dir == "" {
+		return rules, nil
+	}
+
+	modules, err := moduleDirs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, module := range modules {
+		files, err := filepath.Glob(filepath.Join(module.dir, "*.tf"))
+		if err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+			}
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				Logger.Sugar().Errorf("error reading terraform file %s: %v", file, err)
+				return nil, &entities.CustomError{
+					StatusCode: http.StatusInternalServerError,
+					Err:        err,
+				}
+			}
+			for _, rule := range scanIgnoreChanges(string(data)) {
+				rule.Address = module.prefix + rule.Address
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// ParseResourceLocations scans the .tf files of a directory and of the modules it calls for resource blocks and returns
+// the file and line of each block by resource address, e.g. module.network.aws_subnet.private. The address has no
+// instance keys, see entities.ConfigAddress. Like ParseLifecycleIgnoreChanges it is a line based scanner
+func ParseResourceLocations(dir string) (map[string]*entities.SourceLocation, error) {
+	locations := make(map[string]*entities.SourceLocation)
+	if dir == "" {
+		return locations, nil
+	}
+
+	modules, err := moduleDirs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, module := range modules {
+		files, err := filepath.Glob(filepath.Join(module.dir, "*.tf"))
+		if err != nil {
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+			}
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				Logger.Sugar().Errorf("error reading terraform file %s: %v", file, err)
+				return nil, &entities.CustomError{
+					StatusCode: http.StatusInternalServerError,
+					Err:        err,
+				}
+			}
+			for address, line := range scanResourceLines(string(data)) {
+				locations[module.prefix+address] = &entities.SourceLocation{File: filepath.ToSlash(file), Line: line}
+			}
+		}
+	}
+
+	return locations, nil
+}
+
+// terraformModule is a directory of terraform code with the address prefix of its resources, e.g. module.network.
+type terraformModule struct {
+	prefix string
+	dir    string
+}
+
+// modulesManifest is the .terraform/modules/modules.json file written by terraform init, the key of a module is its
+// path of module names, e.g. network.subnets
+type modulesManifest struct {
+	Modules []struct {
+		Key string `json:"Key"`
+		Dir string `json:"Dir"`
+	} `json:"Modules"`
+}
+
+// moduleDirs returns the root directory and the directories of the modules it calls. The modules installed by
+// terraform init are read from its manifest, which also covers the registry and git sources, otherwise only the
+// module calls with a local source are followed
+func moduleDirs(dir string) ([]terraformModule, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ".terraform", "modules", "modules.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return localModuleDirs(dir, "", map[string]bool{})
+	}
+	if err != nil {
+		Logger.Sugar().Errorf("error reading terraform modules manifest: %v", err)
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+		}
+	}
+	manifest := &modulesManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		Logger.Sugar().Errorf("error parsing terraform modules manifest: %v", err)
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+	}
+
+	modules := []terraformModule{{dir: dir}}
+	for _, module := range manifest.Modules {
+		if module.Key == "" {
+			continue
+		}
+		modules = append(modules, terraformModule{
+			prefix: "module." + strings.Join(strings.Split(module.Key, "."), ".module.") + ".",
+			dir:    filepath.Join(dir, filepath.FromSlash(module.Dir)),
+		})
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].prefix < modules[j].prefix
+	})
+	return modules, nil
+}
+
+// localModuleDirs returns a directory and the directories of the module calls with a local source, recursively. A
+// module called twice is returned under both prefixes, calling are the directories above it, which guard against
+// modules calling each other
+func localModuleDirs(dir, prefix string, calling map[string]bool) ([]terraformModule, error) {
+	modules := []terraformModule{{prefix: prefix, dir: dir}}
+	if calling[filepath.Clean(dir)] {
+		return modules, nil
+	}
+	calling[filepath.Clean(dir)] = true
+	defer delete(calling, filepath.Clean(dir))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			Logger.Sugar().Errorf("error reading terraform file %s: %v", file, err)
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+		for name, source := range scanModuleSources(string(data)) {
+			if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+				continue
+			}
+			children, err := localModuleDirs(filepath.Join(dir, filepath.FromSlash(source)), prefix+"module."+name+".", calling)
+			if err != nil {
+				return nil, err
+			}
+			modules = append(modules, children...)
+		}
+	}
+	return modules, nil
+}
+
+// scanModuleSources returns the source of every top level module block in the HCL source by module name
+func scanModuleSources(source string) map[string]string {
+	sources := make(map[string]string)
+	source = stripLineComment(blockCommentRegex.ReplaceAllString(source, ""))
+	module := ""
+	depth := 0
+	for _, line := range strings.Split(source, "\n") {
+		if depth == 0 {
+			module = ""
+			if match := moduleBlockRegex.FindStringSubmatch(line); match != nil {
+				module = match[1]
+			}
+		}
+		// a one line block has its source after the brace
+		attribute := line
+		if _, rest, ok := strings.Cut(line, "{"); ok && depth == 0 {
+			attribute = rest
+		}
+		if module != "" && depth <= 1 {
+			if match := moduleSourceRegex.FindStringSubmatch(attribute); match != nil {
+				sources[module] = match[1]
+			}
+		}
+		if depth += strings.Count(line, "{") - strings.Count(line, "}"); depth < 0 {
+			depth = 0
+		}
+	}
+	return sources
+}
+
+// scanResourceLines returns the line, counted from 1, of every top level resource block in the HCL source
+func scanResourceLines(source string) map[string]int {
+	lines := make(map[string]int)
+	// block comments are blanked out line by line so that the lines keep their numbers
+	source = blockCommentRegex.ReplaceAllStringFunc(source, func(comment string) string {
+		return strings.Repeat("\n", strings.Count(comment, "\n"))
+	})
+	depth := 0
+	for i, line := range strings.Split(stripLineComment(source), "\n") {
+		if depth == 0 {
+			if match := resourceBlockRegex.FindStringSubmatch(line); match != nil {
+				lines[match[1]+"."+match[2]] = i + 1
+			}
+		}
+		if depth += strings.Count(line, "{") - strings.Count(line, "}"); depth < 0 {
+			depth = 0
+		}
+	}
+	return lines
+}
+
+// scanIgnoreChanges extracts the ignore_changes lists of every resource block in the HCL source
+func scanIgnoreChanges(source string) []*entities.IgnoreRule {
+	rules := make([]*entities.IgnoreRule, 0)
+	source = stripLineComment(blockCommentRegex.ReplaceAllString(source, ""))
+
+	var address, listAddress string
+	var list strings.Builder
+	depth, listDepth := 0, 0
+	for _, line := range strings.Split(source, "\n") {
+		if depth == 0 {
+			if match := resourceBlockRegex.FindStringSubmatch(line); match != nil {
+				address = match[1] + "." + match[2]
+			}
+		}
+		current := address
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth <= 0 {
+			depth = 0
+			address = ""
+		}
+
+		// the list may span several lines, so keep collecting it until its brackets are balanced
+		if listDepth == 0 {
+			match := ignoreChangesRegex.FindStringSubmatchIndex(line)
+			if match == nil || current == "" {
+				continue
+			}
+			if line[match[2]:match[3]] == "all" {
+				rules = append(rules, &entities.IgnoreRule{Address: current, Attributes: []string{"*"}, Reason: "lifecycle ignore_changes"})
+				continue
+			}
+			line = line[match[2]:]
+			listAddress = current
+			list.Reset()
+		}
+		for i := 0; i < len(line); i++ {
+			switch line[i] {
+			case '[':
+				listDepth++
+			case ']':
+				listDepth--
+			}
+			list.WriteByte(line[i])
+			if listDepth == 0 {
+				rules = append(rules, &entities.IgnoreRule{Address: listAddress, Attributes: splitIgnoreChanges(list.String()), Reason: "lifecycle ignore_changes"})
+				break
+			}
+		}
+	}
+
+	return rules
+}
+
+// splitIgnoreChanges turns `[tags["Name"], ami]` into attribute paths like tags.Name
+func splitIgnoreChanges(list string) []string {
+	list = strings.TrimSpace(list)
+	list = strings.TrimSuffix(strings.TrimPrefix(list, "["), "]")
+	list = indexKeyRegex.ReplaceAllString(list, ".$1")
+	attributes := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			attributes = append(attributes, item)
+		}
+	}
+	return attributes
+}
+
+// stripLineComment drops # and // comments that are not inside a string
+func stripLineComment(source string) string {
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		inString := false
+		for j := 0; j < len(line); j++ {
+			switch {
+			case line[j] == '"' && (j == 0 || line[j-1] != '\\'):
+				inString = !inString
+			case !inString && (line[j] == '#' || strings.HasPrefix(line[j:], "//")):
+				lines[i] = line[:j]
+				j = len(line)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScanIgnoreChanges(t *testing.T) {
+	Convey("single line and multi line ignore_changes", t, func() {
+		rules := scanIgnoreChanges(`
+resource "aws_instance" "web" {
+  instance_type = "t2.micro" # lifecycle { ignore_changes = [ami] }
+  lifecycle {
+    ignore_changes = [tags["Owner"], instance_type]
+  }
+}
+
+/* resource "aws_instance" "commented" {
+  lifecycle { ignore_changes = all }
+} */
+
+resource "aws_instance" "db" {
+  lifecycle {
+    ignore_changes = [
+      tags,
+      root_block_device[0].volume_size,
+    ]
+  }
+}
+
+resource "aws_instance" "all" { lifecycle { ignore_changes = all } }
+`)
+		So(len(rules), ShouldEqual, 3)
+		So(rules[0].Address, ShouldEqual, "aws_instance.web")
+		So(rules[0].Attributes, ShouldResemble, []string{"tags.Owner", "instance_type"})
+		So(rules[1].Address, ShouldEqual, "aws_instance.db")
+		So(rules[1].Attributes, ShouldResemble, []string{"tags", "root_block_device[0].volume_size"})
+		So(rules[2].Address, ShouldEqual, "aws_instance.all")
+		So(rules[2].Attributes, ShouldResemble, []string{"*"})
+	})
+
+	Convey("no terraform directory configured", t, func() {
+		rules, err := ParseLifecycleIgnoreChanges("")
+		So(err, ShouldBeNil)
+		So(len(rules), ShouldEqual, 0)
+	})
+
+	Convey("the ignore_changes of local modules are keyed by module path", t, func() {
+		dir := t.TempDir()
+		writeTerraform(dir, "main.tf", `module "network" {
+  source = "./modules/network"
+}
+
+resource "aws_instance" "web" {
+  lifecycle { ignore_changes = [ami] }
+}
+`)
+		writeTerraform(dir, "modules/network/main.tf", `resource "aws_subnet" "private" {
+  lifecycle { ignore_changes = [tags] }
+}
+`)
+
+		rules, err := ParseLifecycleIgnoreChanges(dir)
+		So(err, ShouldBeNil)
+		addresses := make(map[string][]string)
+		for _, rule := range rules {
+			addresses[rule.Address] = rule.Attributes
+		}
+		So(addresses, ShouldResemble, map[string][]string{
+			"aws_instance.web":                  {"ami"},
+			"module.network.aws_subnet.private": {"tags"},
+		})
+	})
+}
+
+func TestScanResourceLines(t *testing.T) {
+	Convey("resource blocks are located by address, comments keep the line numbers", t, func() {
+		lines := scanResourceLines(`# resource "aws_instance" "hash" {}
+resource "aws_instance" "web" {
+  tags = { Name = "web" }
+  /* resource "aws_instance" "commented" {
+  } */
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+`)
+		So(lines, ShouldResemble, map[string]int{"aws_instance.web": 2, "aws_s3_bucket.logs": 8})
+	})
+
+	Convey("no terraform directory configured", t, func() {
+		locations, err := ParseResourceLocations("")
+		So(err, ShouldBeNil)
+		So(locations, ShouldBeEmpty)
+	})
+
+	Convey("the resource blocks of the local modules are located by module path", t, func() {
+		dir := t.TempDir()
+		writeTerraform(dir, "main.tf", `resource "aws_instance" "web" {}
+
+module "network" {
+  source = "./modules/network"
+}
+
+module "edge" { source = "./modules/network" }
+
+module "registry" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`)
+		writeTerraform(dir, "modules/network/main.tf", `module "subnets" {
+  source = "../subnets"
+}
+
+resource "aws_vpc" "main" {}
+`)
+		writeTerraform(dir, "modules/subnets/main.tf", `resource "aws_subnet" "private" {}`)
+
+		locations, err := ParseResourceLocations(dir)
+		So(err, ShouldBeNil)
+		lines := make(map[string]string)
+		for address, location := range locations {
+			relative, _ := filepath.Rel(dir, location.File)
+			lines[address] = fmt.Sprintf("%s:%d", filepath.ToSlash(relative), location.Line)
+		}
+		So(lines, ShouldResemble, map[string]string{
+			"aws_instance.web":                                 "main.tf:1",
+			"module.network.aws_vpc.main":                      "modules/network/main.tf:5",
+			"module.network.module.subnets.aws_subnet.private": "modules/subnets/main.tf:1",
+			"module.edge.aws_vpc.main":                         "modules/network/main.tf:5",
+			"module.edge.module.subnets.aws_subnet.private":    "modules/subnets/main.tf:1",
+		})
+	})
+
+	Convey("the modules installed by terraform init are read from its manifest", t, func() {
+		dir := t.TempDir()
+		writeTerraform(dir, "main.tf", `module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`)
+		writeTerraform(dir, ".terraform/modules/vpc/main.tf", `resource "aws_vpc" "this" {}`)
+		writeTerraform(dir, ".terraform/modules/modules.json",
+			`{"Modules":[{"Key":"","Source":"","Dir":"."},{"Key":"vpc","Source":"registry.terraform.io/terraform-aws-modules/vpc/aws","Dir":".terraform/modules/vpc"}]}`)
+
+		locations, err := ParseResourceLocations(dir)
+		So(err, ShouldBeNil)
+		So(locations, ShouldContainKey, "module.vpc.aws_vpc.this")
+		So(locations["module.vpc.aws_vpc.this"].Line, ShouldEqual, 1)
+	})
+}
+
+// writeTerraform writes a file of terraform code under dir, creating its directories
+func writeTerraform(dir, name, content string) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	So(os.MkdirAll(filepath.Dir(path), 0755), ShouldBeNil)
+	So(os.WriteFile(path, []byte(content), 0644), ShouldBeNil)
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/driftreport/entities"
+)
+
+// NormalizeJSON returns the canonical form of a JSON document, with sorted keys and without whitespace, so that two
+// documents only differing in formatting are equal. An empty document stays empty
+func NormalizeJSON(document string) (string, error) {
+	if strings.TrimSpace(document) == "" {
+		return "", nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		return "", &entities.CustomError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return "", &entities.CustomError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+		}
+	}
+	return string(normalized), nil
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/driftreport/entities"
+)
+
+// policyLists are the statement elements that accept a single string or a list of strings
+var policyLists = []string{"Action", "NotAction", "Resource", "NotResource"}
+
+// NormalizePolicyDocument returns the canonical form of an IAM policy document, so that documents granting the same
+// permissions are equal. The IAM API returns URL-encoded documents, a single statement or string is turned into a
+// list, lists are sorted and statements are ordered by their content
+func NormalizePolicyDocument(document string) (string, error) {
+	if strings.HasPrefix(strings.TrimSpace(document), "%") {
+		decoded, err := url.QueryUnescape(document)
+		if err != nil {
+			return "", &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+			}
+		}
+		document = decoded
+	}
+	normalized, err := NormalizeJSON(document)
+	if err != nil || normalized == "" {
+		return normalized, err
+	}
+
+	policy := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(normalized), &policy); err != nil {
+		return "", &entities.CustomError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+	}
+	statements, ok := policy["Statement"].([]interface{})
+	if !ok && policy["Statement"] != nil {
+		statements = []interface{}{policy["Statement"]}
+	}
+	for _, statement := range statements {
+		statement, ok := statement.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range policyLists {
+			if value, ok := statement[key]; ok {
+				statement[key] = sortedList(value)
+			}
+		}
+		// principals and condition values are maps of single strings or lists too, e.g. {"AWS": "arn:..."}
+		for _, key := range []string{"Principal", "NotPrincipal"} {
+			if principal, ok := statement[key].(map[string]interface{}); ok {
+				for name, value := range principal {
+					principal[name] = sortedList(value)
+				}
+			}
+		}
+		if condition, ok := statement["Condition"].(map[string]interface{}); ok {
+			for _, operator := range condition {
+				if values, ok := operator.(map[string]interface{}); ok {
+					for name, value := range values {
+						values[name] = sortedList(value)
+					}
+				}
+			}
+		}
+	}
+	if statements != nil {
+		sort.Slice(statements, func(i, j int) bool {
+			return canonical(statements[i]) < canonical(statements[j])
+		})
+		policy["Statement"] = statements
+	}
+	return canonical(policy), nil
+}
+
+// PolicyDifference compares two policy documents by their canonical form and returns the difference of the attribute,
+// or nil when both grant the same permissions
+func PolicyDifference(attribute, tfPolicy, awsPolicy string) (*entities.Difference, error) {
+	tfDocument, err := NormalizePolicyDocument(tfPolicy)
+	if err != nil {
+		return nil, err
+	}
+	awsDocument, err := NormalizePolicyDocument(awsPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if tfDocument == awsDocument {
+		return nil, nil
+	}
+	var expected, actual interface{}
+	if tfDocument != "" {
+		expected = tfDocument
+	}
+	if awsDocument != "" {
+		actual = awsDocument
+	}
+	return entities.NewDifference(attribute, expected, actual), nil
+}
+
+// sortedList turns a single string into a list and sorts the strings of a list
+func sortedList(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return []interface{}{v}
+	case []interface{}:
+		sort.Slice(v, func(i, j int) bool {
+			return canonical(v[i]) < canonical(v[j])
+		})
+		return v
+	}
+	return value
+}
+
+// canonical returns the JSON of a decoded value, map keys are sorted by encoding/json
+func canonical(value interface{}) string {
+	document, _ := json.Marshal(value)
+	return string(document)
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPolicyDifference(t *testing.T) {
+	tfPolicy := `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {"Effect": "Allow", "Action": ["s3:PutObject", "s3:GetObject"], "Resource": "arn:aws:s3:::logs/*"},
+    {"Effect": "Allow", "Action": "sqs:SendMessage", "Resource": ["arn:aws:sqs:us-west-2:123456789012:events"]}
+  ]
+}`
+
+	Convey("documents only differing in statement order, list order and single strings are equal", t, func() {
+		awsPolicy := url.QueryEscape(`{"Statement":[{"Action":["sqs:SendMessage"],"Effect":"Allow","Resource":"arn:aws:sqs:us-west-2:123456789012:events"},` +
+			`{"Action":["s3:GetObject","s3:PutObject"],"Effect":"Allow","Resource":["arn:aws:s3:::logs/*"]}],"Version":"2012-10-17"}`)
+		difference, err := PolicyDifference("policy", tfPolicy, awsPolicy)
+		So(err, ShouldBeNil)
+		So(difference, ShouldBeNil)
+	})
+
+	Convey("an action added outside terraform is reported", t, func() {
+		awsPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:GetObject","s3:PutObject","s3:DeleteObject"],"Resource":"arn:aws:s3:::logs/*"},` +
+			`{"Effect":"Allow","Action":"sqs:SendMessage","Resource":"arn:aws:sqs:us-west-2:123456789012:events"}]}`
+		difference, err := PolicyDifference("policy", tfPolicy, awsPolicy)
+		So(err, ShouldBeNil)
+		So(difference, ShouldNotBeNil)
+		So(difference.Kind, ShouldEqual, "changed")
+		So(difference.Actual, ShouldContainSubstring, "s3:DeleteObject")
+	})
+
+	Convey("a single statement object equals a list of one statement", t, func() {
+		first, err := NormalizePolicyDocument(`{"Version":"2012-10-17","Statement":{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}}`)
+		So(err, ShouldBeNil)
+		second, err := NormalizePolicyDocument(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":["ec2.amazonaws.com"]},"Action":["sts:AssumeRole"]}]}`)
+		So(err, ShouldBeNil)
+		So(first, ShouldEqual, second)
+	})
+
+	Convey("a removed policy is reported as removed", t, func() {
+		difference, err := PolicyDifference("policy", tfPolicy, "")
+		So(err, ShouldBeNil)
+		So(difference.Kind, ShouldEqual, "removed")
+	})
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/driftreport/entities"
+)
+
+// LoadIgnoreRules parses the drift rules json file and compiles its rules, an empty path means no rules
+func LoadIgnoreRules(filePath string) (*entities.IgnoreRules, error) {
+	rules := &entities.IgnoreRules{}
+	if filePath == "" {
+		return rules, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		Logger.Sugar().Errorf("error reading drift rules file: %v", err)
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+		}
+	}
+
+	if err := json.Unmarshal(data, rules); err != nil {
+		Logger.Sugar().Errorf("error parsing drift rules file: %v", err)
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+	}
+
+	rules.Compile()
+	return rules, nil
+}
+
+// LoadDriftPolicy parses the drift policy json file and compiles its rules, an empty path gives the default policy
+func LoadDriftPolicy(filePath string) (*entities.DriftPolicy, error) {
+	if filePath == "" {
+		return entities.DefaultDriftPolicy(), nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		Logger.Sugar().Errorf("error reading drift policy file: %v", err)
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+		}
+	}
+
+	policy := &entities.DriftPolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		Logger.Sugar().Errorf("error parsing drift policy file: %v", err)
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+	}
+
+	// reject unknown severities, they would silently rank below info, and store them lowercased for their rank
+	severities := make([]*entities.Severity, 0, len(policy.Rules)+1)
+	if policy.DefaultSeverity != "" {
+		severities = append(severities, &policy.DefaultSeverity)
+	}
+	for _, rule := range policy.Rules {
+		severities = append(severities, &rule.Severity)
+	}
+	for _, severity := range severities {
+		parsed, err := entities.ParseSeverity(string(*severity))
+		if err != nil {
+			Logger.Sugar().Errorf("invalid drift policy file: %v", err)
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+			}
+		}
+		*severity = parsed
+	}
+
+	policy.Compile()
+	return policy, nil
+}
+
+// LoadCustomRules parses the custom drift rules json file, an empty path means no custom rules
+func LoadCustomRules(filePath string) (*entities.CustomRules, error) {
+	customRules := &entities.CustomRules{}
+	if filePath == "" {
+		return customRules, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		Logger.Sugar().Errorf("error reading custom rules file: %v", err)
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+		}
+	}
+
+	if err := json.Unmarshal(data, customRules); err != nil {
+		Logger.Sugar().Errorf("error parsing custom rules file: %v", err)
+		return nil, &entities.CustomError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+	}
+
+	for _, rule := range customRules.Rules {
+		if rule.Severity == "" {
+			rule.Severity = entities.SeverityMedium
+		}
+		severity, err := entities.ParseSeverity(string(rule.Severity))
+		if err != nil {
+			Logger.Sugar().Errorf("invalid custom rule %s: %v", rule.ID, err)
+			return nil, &entities.CustomError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+			}
+		}
+		rule.Severity = severity
+	}
+
+	return customRules, nil
+}
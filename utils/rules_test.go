@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/driftreport/entities"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoadRules(t *testing.T) {
+	logger := InitZapLog()
+	defer logger.Sync() // Flush any buffered log messages
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		So(os.WriteFile(path, []byte(content), 0644), ShouldBeNil)
+		return path
+	}
+
+	Convey("the severities of a drift policy are case insensitive and ranked", t, func() {
+		policy, err := LoadDriftPolicy(writeFile("policy.json",
+			`{"default_severity": "Low", "rules": [{"attribute": "instance_type", "severity": "HIGH"}]}`))
+		So(err, ShouldBeNil)
+		So(policy.DefaultSeverity, ShouldEqual, entities.SeverityLow)
+		So(policy.Rules[0].Severity, ShouldEqual, entities.SeverityHigh)
+		So(policy.Rules[0].Severity.Rank(), ShouldEqual, entities.SeverityHigh.Rank())
+	})
+
+	Convey("a drift policy with an unknown severity is rejected", t, func() {
+		_, err := LoadDriftPolicy(writeFile("invalid.json", `{"rules": [{"attribute": "instance_type", "severity": "urgent"}]}`))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("the severities of custom rules are case insensitive and default to medium", t, func() {
+		customRules, err := LoadCustomRules(writeFile("custom.json", `{"rules": [`+
+			`{"id": "instance-family", "condition": "true", "severity": "Critical"},`+
+			`{"id": "tagged", "condition": "true"}]}`))
+		So(err, ShouldBeNil)
+		So(customRules.Rules[0].Severity, ShouldEqual, entities.SeverityCritical)
+		So(customRules.Rules[1].Severity, ShouldEqual, entities.SeverityMedium)
+	})
+}
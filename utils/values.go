@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// AppendUnique appends the values that are not in the list yet
+func AppendUnique(values []string, newValues ...string) []string {
+	for _, value := range newValues {
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// StringValue prints a value decoded from the state json, an unset value is an empty string
+func StringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// StringMap converts a map decoded from the state json, e.g. tags, to a map of strings
+func StringMap(value interface{}) map[string]string {
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	stringValues := make(map[string]string, len(values))
+	for key, value := range values {
+		stringValues[key] = StringValue(value)
+	}
+	return stringValues
+}
+
+// StringList converts a list decoded from the state json to a list of strings
+func StringList(value interface{}) []string {
+	values, _ := value.([]interface{})
+	stringValues := make([]string, 0, len(values))
+	for _, value := range values {
+		stringValues = append(stringValues, StringValue(value))
+	}
+	return stringValues
+}
+
+// SortedKeys returns the keys of values in order, so that the differences of a resource are found in the same order
+// from run to run
+func SortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SortBlocks returns a copy of the blocks of a terraform set sorted by one of their attributes, e.g. device_name, so
+// that both sides of a set are compared element by element. Numbers sort by value, whether they were decoded from the
+// state json or read from AWS
+func SortBlocks(blocks []interface{}, key string) []interface{} {
+	sorted := append([]interface{}{}, blocks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		first, _ := sorted[i].(map[string]interface{})
+		second, _ := sorted[j].(map[string]interface{})
+		firstValue, secondValue := StringValue(first[key]), StringValue(second[key])
+		firstNumber, firstErr := strconv.ParseFloat(firstValue, 64)
+		secondNumber, secondErr := strconv.ParseFloat(secondValue, 64)
+		if firstErr == nil && secondErr == nil {
+			return firstNumber < secondNumber
+		}
+		return firstValue < secondValue
+	})
+	return sorted
+}
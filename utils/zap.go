@@ -36,7 +36,7 @@ func InitZapLog() *zap.Logger {
 			MaxAge:     30,               // Number of days to retain log files
 			Compress:   false,            // Whether to compress
 		})
-		infoFileCore := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(infoFileWriteSyncer, zapcore.AddSync(os.Stdout)), lowPriority) // The third and subsequent parameters are the log levels for writing to the file. In ErrorLevel mode, only error - level logs are recorded.
+		infoFileCore := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(infoFileWriteSyncer, zapcore.AddSync(os.Stderr)), lowPriority) // The third and subsequent parameters are the log levels for writing to the file. In ErrorLevel mode, only error - level logs are recorded.
 
 		// Error file writeSyncer
 		errorFileWriteSyncer := zapcore.AddSync(&lumberjack.Logger{
@@ -46,12 +46,12 @@ func InitZapLog() *zap.Logger {
 			MaxAge:     30,                // Number of days to retain log files
 			Compress:   false,             // Whether to compress
 		})
-		errorFileCore := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(errorFileWriteSyncer, zapcore.AddSync(os.Stdout)), highPriority) // The third and subsequent parameters are the log levels for writing to the file. In ErrorLevel mode, only error - level logs are recorded.
+		errorFileCore := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(errorFileWriteSyncer, zapcore.AddSync(os.Stderr)), highPriority) // The third and subsequent parameters are the log levels for writing to the file. In ErrorLevel mode, only error - level logs are recorded.
 		coreArr = append(coreArr, infoFileCore)
 		coreArr = append(coreArr, errorFileCore)
 	} else {
-		infoCore := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(zapcore.AddSync(os.Stdout)), lowPriority)
-		errorCore := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(zapcore.AddSync(os.Stdout)), highPriority)
+		infoCore := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(zapcore.AddSync(os.Stderr)), lowPriority)
+		errorCore := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(zapcore.AddSync(os.Stderr)), highPriority)
 		coreArr = append(coreArr, infoCore)
 		coreArr = append(coreArr, errorCore)
 	}